@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errMissingArgument = errors.New("опции требуется аргумент")
+	errUnknownOption   = errors.New("неизвестная опция")
+	errSeparator       = errors.New("разделитель полей должен быть одним символом")
+	errParallel        = errors.New("число потоков должно быть положительным")
+	errCheck           = errors.New("неизвестный режим проверки")
+)
+
+// checkMode - режим проверки вместо сортировки
+type checkMode int
+
+const (
+	checkNone     checkMode = iota
+	checkDiagnose           // -c: сообщить о первой строке не на своём месте
+	checkQuiet              // -C: только код возврата
+)
+
+// options - результат разбора командной строки
+type options struct {
+	global     keyOptions // Правила сравнения, заданные флагами вне -k
+	keys       []keySpec  // -k, в порядке указания
+	sep        rune       // -t
+	hasSep     bool
+	coll       *collation // --locale
+	stable     bool       // -s
+	unique     bool       // -u
+	check      checkMode  // -c, -C
+	merge      bool       // -m
+	output     string     // -o, "" - стандартный вывод
+	zero       bool       // -z
+	bufferSize int64      // -S
+	tempDir    string     // -T
+	parallel   int        // --parallel
+	files      []string   // Входные файлы, пусто - стандартный ввод
+	help       bool
+}
+
+// delim возвращает разделитель записей
+func (o *options) delim() byte {
+	if o.zero {
+		return 0
+	}
+	return '\n'
+}
+
+// comparator возвращает функцию сравнения строк по всем ключам и флагам
+func (o *options) comparator() *comparator {
+	return newComparator(o.keys, o.global, o.sep, o.hasSep, o.stable, o.unique, o.coll)
+}
+
+// boolFlag - флаг без аргумента: короткое имя (0 - только длинное), длинное имя и действие при его указании
+type boolFlag struct {
+	short byte
+	long  string
+	set   func()
+}
+
+func (o *options) boolFlags() []boolFlag {
+	return []boolFlag{
+		{'n', "numeric-sort", func() { o.global.kind = orderNumeric }},
+		{'M', "month-sort", func() { o.global.kind = orderMonth }},
+		{'h', "human-numeric-sort", func() { o.global.kind = orderHuman }},
+		{'g', "general-numeric-sort", func() { o.global.kind = orderGeneral }},
+		{'V', "version-sort", func() { o.global.kind = orderVersion }},
+		{'r', "reverse", func() { o.global.reverse = true }},
+		{'b', "ignore-leading-blanks", func() { o.global.skipStart, o.global.skipEnd = true, true }},
+		{'f', "ignore-case", func() { o.global.foldCase = true }},
+		{'d', "dictionary-order", func() { o.global.dictionary = true }},
+		{'s', "stable", func() { o.stable = true }},
+		{'u', "unique", func() { o.unique = true }},
+		{'c', "", func() { o.check = checkDiagnose }},
+		{'C', "", func() { o.check = checkQuiet }},
+		{'m', "merge", func() { o.merge = true }},
+		{'z', "zero-terminated", func() { o.zero = true }},
+		{0, "help", func() { o.help = true }},
+	}
+}
+
+// valueFlags - опции со значением: короткое имя (0 - только длинное) и длинное
+func (o *options) valueFlags() map[string]func(string) error {
+	return map[string]func(string) error{
+		"k":                   o.addKey,
+		"key":                 o.addKey,
+		"t":                   o.setSeparator,
+		"field-separator":     o.setSeparator,
+		"S":                   o.setBufferSize,
+		"buffer-size":         o.setBufferSize,
+		"T":                   func(v string) error { o.tempDir = v; return nil },
+		"temporary-directory": func(v string) error { o.tempDir = v; return nil },
+		"parallel":            o.setParallel,
+		"locale":              o.setLocale,
+		"o":                   func(v string) error { o.output = v; return nil },
+		"output":              func(v string) error { o.output = v; return nil },
+	}
+}
+
+func (o *options) addKey(value string) error {
+	key, err := parseKeySpec(value)
+	if err != nil {
+		return err
+	}
+	o.keys = append(o.keys, key)
+	return nil
+}
+
+// setSeparator разбирает -t: один символ, "\0" - нулевой байт
+func (o *options) setSeparator(value string) error {
+	if value == `\0` {
+		o.sep, o.hasSep = 0, true
+		return nil
+	}
+	if utf8.RuneCountInString(value) != 1 {
+		return fmt.Errorf("%w: %q", errSeparator, value)
+	}
+	o.sep, _ = utf8.DecodeRuneInString(value)
+	o.hasSep = true
+	return nil
+}
+
+func (o *options) setBufferSize(value string) error {
+	size, err := parseBufferSize(value)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, value)
+	}
+	o.bufferSize = size
+	return nil
+}
+
+// setCheck разбирает --check[=diagnose-first|quiet|silent]
+func (o *options) setCheck(value string, hasValue bool) error {
+	switch {
+	case !hasValue || value == "diagnose-first":
+		o.check = checkDiagnose
+	case value == "quiet" || value == "silent":
+		o.check = checkQuiet
+	default:
+		return fmt.Errorf("%w: %q", errCheck, value)
+	}
+	return nil
+}
+
+// setLocale разбирает --locale: язык, по правилам которого сравнивается текст
+func (o *options) setLocale(value string) error {
+	coll, err := newCollation(value)
+	if err != nil {
+		return err
+	}
+	o.coll = coll
+	return nil
+}
+
+func (o *options) setParallel(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%w: %q", errParallel, value)
+	}
+	o.parallel = n
+	return nil
+}
+
+// parseArgs разбирает аргументы так же, как GNU sort: sort [ОПЦИИ] [ФАЙЛ...].
+// Короткие флаги можно объединять (-nr), значение пишется слитно или отдельно (-k2, -k 2, -t:),
+// опции могут идти и после файлов, "--" завершает список опций
+func parseArgs(args []string) (*options, error) {
+	o := &options{bufferSize: defaultBufferSize, parallel: runtime.NumCPU()}
+	if o.parallel > maxParallel {
+		o.parallel = maxParallel
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--": // Всё, что дальше, - файлы, даже если начинается с "-"
+			o.files = append(o.files, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			if err := o.parseLong(arg[2:], args, &i); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1: // Одиночный "-" - это стандартный ввод
+			if err := o.parseShort(arg[1:], args, &i); err != nil {
+				return nil, err
+			}
+		default:
+			o.files = append(o.files, arg)
+		}
+	}
+	return o, nil
+}
+
+// parseShort разбирает группу коротких флагов (то, что после "-")
+func (o *options) parseShort(group string, args []string, i *int) error {
+	for j := 0; j < len(group); j++ {
+		name := group[j]
+		if set, ok := o.valueFlags()[string(name)]; ok {
+			value := group[j+1:] // Значение записано слитно: -k2
+			if value == "" {     // Иначе это следующий аргумент: -k 2
+				if *i+1 >= len(args) {
+					return fmt.Errorf("%w: -%c", errMissingArgument, name)
+				}
+				*i++
+				value = args[*i]
+			}
+			return set(value)
+		}
+		known := false
+		for _, f := range o.boolFlags() {
+			if f.short != 0 && f.short == name {
+				f.set()
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: -%c", errUnknownOption, name)
+		}
+	}
+	return nil
+}
+
+// parseLong разбирает длинную опцию (то, что после "--")
+func (o *options) parseLong(arg string, args []string, i *int) error {
+	name, value, hasValue := arg, "", false
+	if eq := strings.IndexByte(arg, '='); eq >= 0 { // Значение записано через "=": --key=2,2
+		name, value, hasValue = arg[:eq], arg[eq+1:], true
+	}
+	if name == "check" { // Значение у --check необязательное и пишется только через "="
+		return o.setCheck(value, hasValue)
+	}
+	if set, ok := o.valueFlags()[name]; ok && len(name) > 1 {
+		if !hasValue {
+			if *i+1 >= len(args) {
+				return fmt.Errorf("%w: --%s", errMissingArgument, name)
+			}
+			*i++
+			value = args[*i]
+		}
+		return set(value)
+	}
+	for _, f := range o.boolFlags() {
+		if f.long != "" && f.long == name && !hasValue {
+			f.set()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: --%s", errUnknownOption, name)
+}
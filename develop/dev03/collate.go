@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+var errLocale = errors.New("неизвестный язык сортировки")
+
+// collation сравнивает текст по правилам языка: Unicode Collation Algorithm с поправками CLDR для этого языка.
+// Так "ёж" оказывается между "ежевика" и "жук", а "Яблоко" - рядом с "яблоко", а не после всех строчных букв.
+// collate.Collator нельзя использовать из нескольких горутин сразу, а сортировка параллельная,
+// поэтому экземпляры берутся из пула: свой на каждое сравнение
+type collation struct {
+	exact  sync.Pool // С учётом регистра: при прочих равных строчная буква идёт раньше прописной
+	folded sync.Pool // -f: регистр не различается совсем
+}
+
+// newCollation возвращает сравнение по правилам языка locale: "ru", "en", "ru_RU.UTF-8" и т. п.
+// "C" и "POSIX" означают побайтное сравнение, для них возвращается nil
+func newCollation(locale string) (*collation, error) {
+	if locale == "C" || locale == "POSIX" {
+		return nil, nil
+	}
+	name := locale
+	if dot := strings.IndexAny(name, ".@"); dot >= 0 { // Кодировку и вариант из имени локали отбрасываем: ru_RU.UTF-8 -> ru_RU
+		name = name[:dot]
+	}
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", errLocale, locale)
+	}
+	if _, _, confidence := language.NewMatcher(collate.Supported()).Match(tag); confidence == language.No {
+		return nil, fmt.Errorf("%w: %q", errLocale, locale)
+	}
+	c := &collation{}
+	c.exact.New = func() interface{} { return collate.New(tag) }
+	c.folded.New = func() interface{} { return collate.New(tag, collate.IgnoreCase) }
+	return c, nil
+}
+
+// compare сравнивает строки по правилам языка
+func (c *collation) compare(a, b string, foldCase bool) int {
+	pool := &c.exact
+	if foldCase {
+		pool = &c.folded
+	}
+	coll := pool.Get().(*collate.Collator)
+	defer pool.Put(coll)
+	return coll.CompareString(a, b)
+}
+
+// dictionaryText оставляет от ключа только буквы, цифры и пробелы (-d). Буквы - любого алфавита, не только латиница
+func dictionaryText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '\t' || r == '\n' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var errBufferSize = errors.New("неверный размер буфера")
+
+const (
+	defaultBufferSize = 64 << 20 // -S по умолчанию: 64 МиБ
+	defaultBatchSize  = 16       // Сколько временных файлов сливать за один проход, как --batch-size в GNU sort
+	maxParallel       = 8        // Больше потоков сортировки GNU sort по умолчанию не берёт
+	lineOverhead      = 32       // Сколько памяти сверх самих байтов уходит на строку: заголовок string и место в слайсе
+)
+
+// sorter сортирует поток строк, не держа его в памяти целиком (внешняя сортировка слиянием):
+// читает куски не больше бюджета памяти, сортирует каждый и сбрасывает во временный файл,
+// а затем сливает временные файлы через кучу. Если весь ввод влез в один кусок, временных файлов нет
+type sorter struct {
+	compare   func(a, b string) int // Порядок строк: <0, 0, >0
+	unique    bool                  // -u: не выводить повторы
+	budget    int64                 // -S: сколько памяти можно занять строками
+	tempDir   string                // -T: где создавать временные файлы, "" - системный каталог
+	parallel  int                   // --parallel: во сколько горутин сортировать кусок
+	batchSize int                   // Сколько временных файлов сливать за раз (и держать открытыми)
+	delim     byte                  // Разделитель записей: '\n', при -z - нулевой байт. Им же разделены записи во временных файлах
+}
+
+// parseBufferSize разбирает -S: число с необязательным суффиксом b (байты), K, M, G, T. Без суффикса - килобайты, как в GNU sort
+func parseBufferSize(value string) (int64, error) {
+	multipliers := map[byte]int64{'b': 1, 'k': 1 << 10, 'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+	multiplier, digits := int64(1<<10), value
+	if value != "" {
+		if m, ok := multipliers[value[len(value)-1]]; ok {
+			multiplier, digits = m, value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/multiplier {
+		return 0, errBufferSize
+	}
+	return n * multiplier, nil
+}
+
+// sort читает строки из in и пишет их в w в отсортированном порядке.
+// В w ничего не пишется, пока ввод не прочитан до конца
+func (s *sorter) sort(in *inputs, w io.Writer) error {
+	defer in.close()
+	bw := bufio.NewWriterSize(w, 64<<10)
+
+	first, eof, err := readChunk(in, s.budget)
+	if err != nil {
+		return err
+	}
+	if eof { // Всё влезло в память - обходимся без временных файлов
+		parallelSort(first, s.compare, s.parallel)
+		if err := s.write(bw, first); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+
+	runs, err := s.spillAll(in, first)
+	if err != nil {
+		removeRuns(runs)
+		return err
+	}
+	if err := s.mergeRuns(runs, bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readChunk читает строки, пока их суммарный размер не превысит budget (но хотя бы одну).
+// eof сообщает, что ввод закончился
+func readChunk(in *inputs, budget int64) (lines []string, eof bool, err error) {
+	var used int64
+	for used < budget {
+		line, err := in.next()
+		if err == io.EOF {
+			return lines, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		lines = append(lines, line)
+		used += int64(len(line)) + lineOverhead
+	}
+	return lines, false, nil
+}
+
+// chunkFile - отсортированный кусок во временном файле
+type chunkFile struct {
+	path string
+}
+
+// spillAll сортирует и сбрасывает во временные файлы первый кусок и все следующие.
+// В памяти одновременно только один кусок: параллельно сортируются его части, а не разные куски
+func (s *sorter) spillAll(in *inputs, first []string) ([]*chunkFile, error) {
+	var runs []*chunkFile
+	for chunk := first; len(chunk) > 0; {
+		parallelSort(chunk, s.compare, s.parallel)
+		path, err := s.spill(chunk)
+		if path != "" {
+			runs = append(runs, &chunkFile{path: path})
+		}
+		if err != nil {
+			return runs, err
+		}
+		next, _, err := readChunk(in, s.budget)
+		if err != nil {
+			return runs, err
+		}
+		chunk = next
+	}
+	return runs, nil
+}
+
+// spill записывает отсортированные строки во временный файл и возвращает его путь
+func (s *sorter) spill(lines []string) (string, error) {
+	f, err := ioutil.TempFile(s.tempDir, "sort")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriterSize(f, 64<<10)
+	for _, line := range lines {
+		bw.WriteString(line)
+		bw.WriteByte(s.delim)
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	return f.Name(), f.Close()
+}
+
+// removeRuns удаляет временные файлы
+func removeRuns(runs []*chunkFile) {
+	for _, r := range runs {
+		if r.path != "" {
+			os.Remove(r.path)
+		}
+	}
+}
+
+// mergeRuns сливает временные файлы в w. Если их больше batchSize, сначала сливает их группами
+// в новые временные файлы: одновременно открыто не больше batchSize файлов
+func (s *sorter) mergeRuns(runs []*chunkFile, w *bufio.Writer) error {
+	defer func() { removeRuns(runs) }() // Удаляем и промежуточные файлы последнего прохода
+	for len(runs) > s.batchSize {
+		var merged []*chunkFile
+		for i := 0; i < len(runs); i += s.batchSize {
+			end := i + s.batchSize
+			if end > len(runs) {
+				end = len(runs)
+			}
+			r, err := s.mergeToTemp(runs[i:end])
+			if r != nil {
+				merged = append(merged, r)
+			}
+			removeRuns(runs[i:end])
+			if err != nil {
+				removeRuns(merged)
+				removeRuns(runs[end:])
+				return err
+			}
+		}
+		runs = merged
+	}
+	return s.mergeFiles(runs, w, s.unique)
+}
+
+// mergeToTemp сливает группу временных файлов в новый временный файл
+func (s *sorter) mergeToTemp(runs []*chunkFile) (*chunkFile, error) {
+	f, err := ioutil.TempFile(s.tempDir, "sort")
+	if err != nil {
+		return nil, err
+	}
+	r := &chunkFile{path: f.Name()}
+	bw := bufio.NewWriterSize(f, 64<<10)
+	err = s.mergeFiles(runs, bw, false) // Повторы убираем только при окончательном слиянии
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return r, err
+}
+
+// mergeFiles открывает временные файлы и сливает их в w
+func (s *sorter) mergeFiles(runs []*chunkFile, w *bufio.Writer, unique bool) error {
+	sources := make([]*bufio.Reader, 0, len(runs))
+	for _, r := range runs {
+		f, err := os.Open(r.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		sources = append(sources, bufio.NewReaderSize(f, 64<<10))
+	}
+	return s.mergeSources(sources, w, unique)
+}
+
+// mergeInputs сливает уже отсортированные входные файлы в w, не сортируя их заново (-m).
+// Все файлы открыты одновременно; "-" среди них читается как стандартный ввод
+func (s *sorter) mergeInputs(names []string, stdin io.Reader, w io.Writer) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	sources := make([]*bufio.Reader, 0, len(names))
+	for _, name := range names {
+		r, err := openInput(name, stdin)
+		if err != nil {
+			return err
+		}
+		if name != "-" {
+			defer r.(io.Closer).Close()
+		}
+		sources = append(sources, bufio.NewReaderSize(r, 64<<10))
+	}
+	bw := bufio.NewWriterSize(w, 64<<10)
+	if err := s.mergeSources(sources, bw, s.unique); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// mergeSources сливает источники в w, завершая каждую запись разделителем
+func (s *sorter) mergeSources(sources []*bufio.Reader, w *bufio.Writer, unique bool) error {
+	return merge(sources, s.compare, s.delim, func(line string) error {
+		_, err := w.WriteString(line)
+		if err == nil {
+			err = w.WriteByte(s.delim)
+		}
+		return err
+	}, unique)
+}
+
+// mergeItem - текущая строка одного из сливаемых источников
+type mergeItem struct {
+	line   string
+	source int // Номер источника: при равных строках первой идёт строка из более раннего источника
+}
+
+// mergeHeap - минимальная куча текущих строк всех источников
+type mergeHeap struct {
+	items   []mergeItem
+	compare func(a, b string) int
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if c := h.compare(h.items[i].line, h.items[j].line); c != 0 {
+		return c < 0
+	}
+	return h.items[i].source < h.items[j].source
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// merge сливает отсортированные источники (k-путевое слияние через кучу) и передаёт строки в emit по порядку.
+// Записи в источниках разделены delim
+// При unique из подряд идущих равных строк передаётся только первая
+func merge(sources []*bufio.Reader, compare func(a, b string) int, delim byte, emit func(string) error, unique bool) error {
+	h := &mergeHeap{compare: compare}
+	for i, src := range sources {
+		line, err := readRecord(src, delim)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.items = append(h.items, mergeItem{line: line, source: i})
+	}
+	heap.Init(h)
+
+	var prev string
+	emitted := false
+	for h.Len() > 0 {
+		top := h.items[0]
+		if !unique || !emitted || compare(prev, top.line) != 0 {
+			if err := emit(top.line); err != nil {
+				return err
+			}
+			prev, emitted = top.line, true
+		}
+		line, err := readRecord(sources[top.source], delim)
+		switch {
+		case err == io.EOF: // Источник исчерпан
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default: // Заменяем вершину следующей строкой того же источника
+			h.items[0].line = line
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
+
+// write выводит отсортированные строки (при unique - без повторов)
+func (s *sorter) write(w *bufio.Writer, lines []string) error {
+	for i, line := range lines {
+		if s.unique && i > 0 && s.compare(lines[i-1], line) == 0 {
+			continue
+		}
+		if _, err := w.WriteString(line); err != nil {
+			return err
+		}
+		if err := w.WriteByte(s.delim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parallelSort устойчиво сортирует строки в p горутин: каждая сортирует свою часть, затем части попарно сливаются
+func parallelSort(lines []string, compare func(a, b string) int, p int) {
+	less := func(s []string) func(i, j int) bool {
+		return func(i, j int) bool { return compare(s[i], s[j]) < 0 }
+	}
+	if p <= 1 || len(lines) < 2*p {
+		sort.SliceStable(lines, less(lines))
+		return
+	}
+	bounds := make([]int, p+1)
+	for i := range bounds {
+		bounds[i] = len(lines) * i / p
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < p; i++ {
+		part := lines[bounds[i]:bounds[i+1]]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sort.SliceStable(part, less(part))
+		}()
+	}
+	wg.Wait()
+
+	buf := make([]string, len(lines))
+	for width := 1; width < p; width *= 2 { // Сливаем соседние части, удваивая их размер
+		for i := 0; i+width < p; i += 2 * width {
+			end := i + 2*width
+			if end > p {
+				end = p
+			}
+			lo, mid, hi := bounds[i], bounds[i+width], bounds[end]
+			mergeSlices(buf[lo:hi], lines[lo:mid], lines[mid:hi], compare)
+			copy(lines[lo:hi], buf[lo:hi])
+		}
+	}
+}
+
+// mergeSlices устойчиво сливает отсортированные a и b в dst
+func mergeSlices(dst, a, b []string, compare func(a, b string) int) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if compare(b[j], a[i]) < 0 { // При равенстве первой идёт строка из a - сортировка остаётся устойчивой
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
@@ -0,0 +1,5 @@
+module dev03
+
+go 1.17
+
+require golang.org/x/text v0.3.8
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// inputs читает записи из нескольких входных файлов подряд, как если бы это был один поток.
+// Файлы открываются по одному, когда до них доходит очередь, "-" - стандартный ввод.
+// Граница файлов всегда разделяет записи, даже если последняя запись файла не заканчивается разделителем
+type inputs struct {
+	names []string  // Ещё не открытые файлы
+	stdin io.Reader // Что читать вместо "-"
+	delim byte      // Разделитель записей
+	cur   *bufio.Reader
+	file  io.Closer // Открытый сейчас файл, nil - стандартный ввод или ничего
+}
+
+// newInputs готовит чтение файлов names, без файлов - стандартного ввода
+func newInputs(names []string, stdin io.Reader, delim byte) *inputs {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	return &inputs{names: names, stdin: stdin, delim: delim}
+}
+
+// next возвращает следующую запись без разделителя или io.EOF, когда закончился последний файл
+func (in *inputs) next() (string, error) {
+	for {
+		if in.cur == nil {
+			if len(in.names) == 0 {
+				return "", io.EOF
+			}
+			name := in.names[0]
+			r, err := openInput(name, in.stdin)
+			if err != nil {
+				return "", err
+			}
+			in.names = in.names[1:]
+			in.cur = bufio.NewReaderSize(r, 64<<10)
+			if name != "-" {
+				in.file = r.(io.Closer)
+			}
+		}
+		rec, err := readRecord(in.cur, in.delim)
+		if err != io.EOF {
+			return rec, err
+		}
+		in.close() // Файл кончился - переходим к следующему
+	}
+}
+
+// close закрывает текущий файл. Стандартный ввод не закрывается
+func (in *inputs) close() {
+	if in.file != nil {
+		in.file.Close()
+	}
+	in.cur, in.file = nil, nil
+}
+
+// openInput открывает входной файл, "-" - стандартный ввод
+func openInput(name string, stdin io.Reader) (io.Reader, error) {
+	if name == "-" {
+		return stdin, nil
+	}
+	return os.Open(name)
+}
+
+// readRecord читает запись до разделителя delim и возвращает её без разделителя.
+// Последняя запись может и не заканчиваться разделителем
+func readRecord(br *bufio.Reader, delim byte) (string, error) {
+	rec, err := br.ReadString(delim)
+	if err == io.EOF && rec != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(rec, string(delim)), err
+}
+
+// outputFile - файл вывода -o. Он создаётся (и обрезается) только при первой записи или при закрытии:
+// сортировка начинает писать, лишь прочитав весь ввод, поэтому -o может совпадать с одним из входных файлов
+type outputFile struct {
+	path string
+	f    *os.File
+}
+
+func (o *outputFile) Write(p []byte) (int, error) {
+	if err := o.open(); err != nil {
+		return 0, err
+	}
+	return o.f.Write(p)
+}
+
+// Close закрывает файл вывода. Если ничего не было записано, файл всё равно создаётся пустым
+func (o *outputFile) Close() error {
+	if err := o.open(); err != nil {
+		return err
+	}
+	return o.f.Close()
+}
+
+func (o *outputFile) open() error {
+	if o.f != nil {
+		return nil
+	}
+	f, err := os.Create(o.path)
+	if err != nil {
+		return err
+	}
+	o.f = f
+	return nil
+}
+
+// copyOverlapping заменяет входные файлы, которые совпадают с файлом вывода, их временными копиями.
+// Нужно при -m: слияние пишет результат, ещё не дочитав входные файлы.
+// Возвращает новый список файлов и копии, которые надо удалить после слияния
+func (s *sorter) copyOverlapping(names []string, output string) ([]string, []*chunkFile, error) {
+	out, err := os.Stat(output)
+	if err != nil { // Файла вывода ещё нет - совпадать не с чем
+		return names, nil, nil
+	}
+	names = append([]string(nil), names...)
+	var copies []*chunkFile
+	for i, name := range names {
+		if name == "-" {
+			continue
+		}
+		if in, err := os.Stat(name); err != nil || !os.SameFile(in, out) {
+			continue
+		}
+		path, err := s.copyToTemp(name)
+		if path != "" {
+			copies = append(copies, &chunkFile{path: path})
+		}
+		if err != nil {
+			return nil, copies, err
+		}
+		names[i] = path
+	}
+	return names, copies, nil
+}
+
+// copyToTemp копирует файл во временный файл и возвращает путь копии
+func (s *sorter) copyToTemp(name string) (string, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst, err := ioutil.TempFile(s.tempDir, "sort")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return dst.Name(), err
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	errKeySpec = errors.New("неверное описание ключа")
+	errKeyZero = errors.New("номер поля в ключе должен быть положительным")
+)
+
+// orderKind - как сравнивать текст ключа
+type orderKind int
+
+const (
+	orderText    orderKind = iota // Посимвольно: по байтам или, с --locale, по правилам языка
+	orderNumeric                  // -n: по числовому значению
+	orderMonth                    // -M: по названию месяца
+	orderHuman                    // -h: по числу с суффиксом размера (2K, 1G)
+	orderGeneral                  // -g: по значению числа с плавающей точкой (1e3, inf, nan)
+	orderVersion                  // -V: по номерам версий внутри текста (file2 < file10)
+)
+
+// keyOptions - правила сравнения одного ключа (или глобальные, заданные флагами вне -k)
+type keyOptions struct {
+	kind       orderKind
+	reverse    bool // r: обратный порядок
+	skipStart  bool // b у начала ключа: пропустить пробелы перед началом
+	skipEnd    bool // b у конца ключа: пропустить пробелы перед концом
+	foldCase   bool // f: не различать строчные и прописные буквы
+	dictionary bool // d: сравнивать только буквы, цифры и пробелы
+	hasOptions bool // Был ли у ключа хоть один модификатор; ключ без модификаторов наследует глобальные
+}
+
+// keySpec - ключ сортировки -k POS1[,POS2], где POS - F[.C][модификаторы].
+// Поля и символы нумеруются с единицы, символ 0 в конце ключа - конец поля
+type keySpec struct {
+	startField, startChar int
+	endField, endChar     int // endField == 0 - ключ продолжается до конца строки
+	opts                  keyOptions
+}
+
+// parseKeySpec разбирает значение -k
+func parseKeySpec(spec string) (keySpec, error) {
+	var key keySpec
+	start, end := spec, ""
+	if comma := strings.IndexByte(spec, ','); comma >= 0 {
+		start, end = spec[:comma], spec[comma+1:]
+	}
+	var err error
+	key.startField, key.startChar, err = parsePosition(start, &key.opts, true)
+	if err != nil {
+		return keySpec{}, fmt.Errorf("%w: %q", err, spec)
+	}
+	if key.startChar == 0 { // Символ начала ключа нумеруется с единицы, нуля там не бывает
+		return keySpec{}, fmt.Errorf("%w: %q", errKeySpec, spec)
+	}
+	if end != "" {
+		key.endField, key.endChar, err = parsePosition(end, &key.opts, false)
+		if err != nil {
+			return keySpec{}, fmt.Errorf("%w: %q", err, spec)
+		}
+	}
+	return key, nil
+}
+
+// parsePosition разбирает F[.C][модификаторы]. Модификаторы пишутся в opts; b относится к той позиции, где указан
+func parsePosition(pos string, opts *keyOptions, isStart bool) (field, char int, err error) {
+	digits := len(pos) - len(strings.TrimLeft(pos, "0123456789"))
+	if digits == 0 {
+		return 0, 0, errKeySpec
+	}
+	field, _ = strconv.Atoi(pos[:digits])
+	if field == 0 {
+		return 0, 0, errKeyZero
+	}
+	pos = pos[digits:]
+	if isStart {
+		char = 1
+	}
+	if strings.HasPrefix(pos, ".") {
+		pos = pos[1:]
+		digits = len(pos) - len(strings.TrimLeft(pos, "0123456789"))
+		if digits == 0 {
+			return 0, 0, errKeySpec
+		}
+		char, _ = strconv.Atoi(pos[:digits])
+		pos = pos[digits:]
+	}
+	for _, c := range pos {
+		switch c {
+		case 'b':
+			if isStart {
+				opts.skipStart = true
+			} else {
+				opts.skipEnd = true
+			}
+		case 'n':
+			opts.kind = orderNumeric
+		case 'M':
+			opts.kind = orderMonth
+		case 'h':
+			opts.kind = orderHuman
+		case 'g':
+			opts.kind = orderGeneral
+		case 'V':
+			opts.kind = orderVersion
+		case 'f':
+			opts.foldCase = true
+		case 'd':
+			opts.dictionary = true
+		case 'r':
+			opts.reverse = true
+		default:
+			return 0, 0, errKeySpec
+		}
+		opts.hasOptions = true
+	}
+	return field, char, nil
+}
+
+// comparator сравнивает строки по ключам так же, как GNU sort: ключи по очереди, а если все равны -
+// вся строка побайтно (если только не задан -s или -u)
+type comparator struct {
+	keys     []keySpec
+	sep      rune       // -t: разделитель полей
+	hasSep   bool       // Без -t поле - это пробелы и следующие за ними непробельные символы
+	stable   bool       // -s: не сравнивать строки целиком, равные по ключам остаются в исходном порядке
+	unique   bool       // -u: равные по ключам строки считаются повторами, сравнивать их целиком нельзя
+	reverse  bool       // Глобальный -r: действует и на сравнение целых строк
+	lastOnly bool       // Ключей нет и глобальных правил тоже: сравнивается только строка целиком
+	coll     *collation // --locale: текст сравнивается по правилам языка, nil - побайтно
+}
+
+// newComparator собирает comparator. Ключи без своих модификаторов получают глобальные (как в GNU sort).
+// Если ключей нет, но заданы глобальные правила, ключом служит вся строка
+func newComparator(keys []keySpec, global keyOptions, sep rune, hasSep, stable, unique bool, coll *collation) *comparator {
+	c := &comparator{sep: sep, hasSep: hasSep, stable: stable, unique: unique, reverse: global.reverse, coll: coll}
+	for _, key := range keys {
+		if !key.opts.hasOptions {
+			key.opts = global
+		}
+		c.keys = append(c.keys, key)
+	}
+	if len(c.keys) == 0 {
+		if global.kind == orderText && !global.skipStart && !global.skipEnd && !global.foldCase && !global.dictionary {
+			c.lastOnly = true
+		} else {
+			c.keys = []keySpec{{startField: 1, startChar: 1, opts: global}}
+		}
+	}
+	return c
+}
+
+func (c *comparator) compare(a, b string) int {
+	if !c.lastOnly {
+		for i := range c.keys {
+			key := &c.keys[i]
+			diff := compareKeys(c.extract(a, key), c.extract(b, key), &key.opts, c.coll)
+			if key.opts.reverse {
+				diff = -diff
+			}
+			if diff != 0 {
+				return diff
+			}
+		}
+		if c.stable || c.unique {
+			return 0
+		}
+	}
+	diff := 0 // Последнее средство: строки целиком - по правилам языка, а если и так равны, побайтно
+	if c.coll != nil {
+		diff = c.coll.compare(a, b, false)
+	}
+	if diff == 0 {
+		diff = strings.Compare(a, b)
+	}
+	if c.reverse {
+		return -diff
+	}
+	return diff
+}
+
+// isBlank сообщает, разделяет ли символ поля без -t. Перевод строки внутри записи бывает только при -z,
+// и GNU sort тогда тоже считает его пробельным
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// skipField возвращает позицию после поля, начинающегося с i
+func (c *comparator) skipField(line string, i int) int {
+	if c.hasSep {
+		if j := strings.IndexRune(line[i:], c.sep); j >= 0 {
+			return i + j
+		}
+		return len(line)
+	}
+	for i < len(line) && isBlank(line[i]) { // Без -t пробелы перед полем относятся к нему
+		i++
+	}
+	for i < len(line) && !isBlank(line[i]) {
+		i++
+	}
+	return i
+}
+
+// advance сдвигает позицию i на n символов, но не дальше конца строки
+func advance(line string, i, n int) int {
+	for ; n > 0 && i < len(line); n-- {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+	}
+	return i
+}
+
+func skipBlanks(line string, i int) int {
+	for i < len(line) && isBlank(line[i]) {
+		i++
+	}
+	return i
+}
+
+// extract возвращает текст ключа key в строке line
+func (c *comparator) extract(line string, key *keySpec) string {
+	sepLen := utf8.RuneLen(c.sep)
+
+	// Начало: пропускаем startField-1 полей (с разделителями), затем при b - пробелы, затем startChar-1 символов
+	start := 0
+	for n := key.startField - 1; n > 0 && start < len(line); n-- {
+		start = c.skipField(line, start)
+		if c.hasSep && start < len(line) {
+			start += sepLen
+		}
+	}
+	if key.opts.skipStart {
+		start = skipBlanks(line, start)
+	}
+	start = advance(line, start, key.startChar-1)
+
+	// Конец: без POS2 - конец строки; символ 0 - конец поля endField; иначе endChar символов от начала поля
+	end := len(line)
+	if key.endField > 0 {
+		end = 0
+		fields := key.endField - 1
+		if key.endChar == 0 {
+			fields++ // Пропускаем и само поле endField целиком
+		}
+		for n := fields; n > 0 && end < len(line); n-- {
+			end = c.skipField(line, end)
+			if c.hasSep && end < len(line) && (n > 1 || key.endChar != 0) {
+				end += sepLen
+			}
+		}
+		if key.endChar != 0 {
+			if key.opts.skipEnd {
+				end = skipBlanks(line, end)
+			}
+			end = advance(line, end, key.endChar)
+		}
+	}
+	if start >= end {
+		return ""
+	}
+	return line[start:end]
+}
+
+// compareKeys сравнивает тексты ключей по правилам opts (без учёта r).
+// Текст без числовых и прочих правил сравнивается по правилам языка coll, а если он nil - побайтно
+func compareKeys(a, b string, opts *keyOptions, coll *collation) int {
+	switch opts.kind {
+	case orderNumeric:
+		return compareNumeric(a, b)
+	case orderMonth:
+		return monthNumber(a) - monthNumber(b)
+	case orderHuman:
+		return compareHuman(a, b)
+	case orderGeneral:
+		return compareGeneral(a, b)
+	case orderVersion:
+		return compareVersions(a, b)
+	}
+	if opts.dictionary {
+		a, b = dictionaryText(a), dictionaryText(b)
+	}
+	if coll != nil {
+		return coll.compare(a, b, opts.foldCase)
+	}
+	if opts.foldCase {
+		return compareFolded(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
+// compareFolded сравнивает строки, приводя буквы к прописным
+func compareFolded(a, b string) int {
+	for a != "" && b != "" {
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		ra, rb = unicode.ToUpper(ra), unicode.ToUpper(rb)
+		if ra != rb {
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return len(a) - len(b)
+}
+
+// number - разобранное начало ключа для -n: знак, целая часть без ведущих нулей, дробная без хвостовых
+type number struct {
+	negative    bool
+	integer     string
+	fraction    string
+	rest        string // Что осталось после числа (нужно -h для суффикса)
+	isZeroValue bool
+}
+
+// parseNumber разбирает число в начале s, как GNU sort -n: пробелы, необязательный минус, цифры, точка, цифры.
+// Всё, что после числа, игнорируется; строка без числа равна нулю
+func parseNumber(s string) number {
+	i := skipBlanks(s, 0)
+	var n number
+	if i < len(s) && s[i] == '-' {
+		n.negative = true
+		i++
+	}
+	startInt := i
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n.integer = strings.TrimLeft(s[startInt:i], "0")
+	if i < len(s) && s[i] == '.' {
+		i++
+		startFrac := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		n.fraction = strings.TrimRight(s[startFrac:i], "0")
+	}
+	n.rest = s[i:]
+	n.isZeroValue = n.integer == "" && n.fraction == ""
+	return n
+}
+
+// compareNumeric сравнивает числа в начале строк точно, без перевода в float64: длина числа не ограничена
+func compareNumeric(a, b string) int {
+	return compareNumbers(parseNumber(a), parseNumber(b))
+}
+
+func compareNumbers(na, nb number) int {
+	signA, signB := sign(na), sign(nb)
+	if signA != signB {
+		return signA - signB
+	}
+	diff := compareMagnitude(na, nb)
+	if signA < 0 {
+		return -diff
+	}
+	return diff
+}
+
+func sign(n number) int {
+	switch {
+	case n.isZeroValue:
+		return 0 // "-0" равен "0"
+	case n.negative:
+		return -1
+	}
+	return 1
+}
+
+func compareMagnitude(a, b number) int {
+	if len(a.integer) != len(b.integer) { // Ведущих нулей нет - длиннее значит больше
+		return len(a.integer) - len(b.integer)
+	}
+	if c := strings.Compare(a.integer, b.integer); c != 0 {
+		return c
+	}
+	return strings.Compare(a.fraction, b.fraction) // Хвостовых нулей нет - строки сравниваются как дроби
+}
+
+// humanUnits - порядок суффиксов размера для -h
+const humanUnits = "KMGTPEZY"
+
+// compareHuman сравнивает размеры вида 2K, 1.5G: сначала знак и суффикс, затем само число, как GNU sort -h
+func compareHuman(a, b string) int {
+	na, nb := parseNumber(a), parseNumber(b)
+	ua, ub := unitOrder(na), unitOrder(nb)
+	if ua != ub {
+		return ua - ub
+	}
+	return compareNumbers(na, nb)
+}
+
+func unitOrder(n number) int {
+	if n.rest == "" {
+		return 0
+	}
+	unit := n.rest[0]
+	if unit == 'k' {
+		unit = 'K'
+	}
+	order := strings.IndexByte(humanUnits, unit) + 1
+	if n.negative {
+		return -order
+	}
+	return order
+}
+
+// floatPrefix - число в начале ключа для -g: всё, что понимает strconv.ParseFloat (в том числе 1e3, 0x1p4, inf, nan)
+var floatPrefix = regexp.MustCompile(`^[ \t]*[-+]?(?i:infinity|inf|nan|0x(?:[0-9a-f]+\.?[0-9a-f]*|\.[0-9a-f]+)(?:p[-+]?[0-9]+)?|(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:e[-+]?[0-9]+)?)`)
+
+// generalValue возвращает класс и значение ключа для -g. Классы упорядочены так же, как в GNU sort: не числа, NaN, затем сами числа (включая бесконечности)
+func generalValue(s string) (rank int, value float64) {
+	m := floatPrefix.FindString(s)
+	if m == "" {
+		return 0, 0
+	}
+	m = strings.TrimLeft(m, " \t")
+	if lower := strings.ToLower(m); strings.Contains(lower, "0x") && !strings.Contains(lower, "p") {
+		m += "p0" // strtod понимает 0x10 и без двоичной экспоненты, а ParseFloat - нет
+	}
+	v, err := strconv.ParseFloat(m, 64)
+	if errors.Is(err, strconv.ErrRange) && math.IsInf(v, 0) {
+		// GNU sort считает в long double, где 1e999 - ещё конечное число: ставим его перед бесконечностью
+		v = math.Copysign(math.MaxFloat64, v)
+	} else if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, 0
+	}
+	if math.IsNaN(v) {
+		return 1, 0
+	}
+	return 2, v
+}
+
+// compareGeneral сравнивает числа с плавающей точкой в начале строк. В отличие от -n, медленнее и теряет точность,
+// зато понимает экспоненту
+func compareGeneral(a, b string) int {
+	ra, va := generalValue(a)
+	rb, vb := generalValue(b)
+	switch {
+	case ra != rb:
+		return ra - rb
+	case va < vb:
+		return -1
+	case va > vb:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// monthPrefixes - начала названий месяцев для -M: по ним узнаются и полные названия ("January", "января"),
+// и сокращения ("Jan", "янв."). Английские и русские названия не пересекаются, поэтому понимаем оба языка сразу,
+// независимо от локали: данные у нас в основном русские, а даты в логах часто английские
+var monthPrefixes = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	"янв": 1, "фев": 2, "мар": 3, "апр": 4, "май": 5, "мая": 5,
+	"июн": 6, "июл": 7, "авг": 8, "сен": 9, "окт": 10, "ноя": 11, "дек": 12,
+}
+
+// monthPrefixLen - сколько первых букв названия сравнивается с monthPrefixes
+const monthPrefixLen = 3
+
+// monthNumber возвращает номер месяца (1-12) по первым буквам ключа без учёта регистра, 0 - не месяц.
+// Как и в GNU sort, пробелы в начале ключа пропускаются, а всё после сокращения не проверяется
+func monthNumber(s string) int {
+	s = s[skipBlanks(s, 0):]
+	var prefix strings.Builder
+	n := 0
+	for _, r := range s {
+		if n == monthPrefixLen {
+			break
+		}
+		if !unicode.IsLetter(r) {
+			return 0
+		}
+		prefix.WriteRune(unicode.ToLower(r))
+		n++
+	}
+	return monthPrefixes[prefix.String()]
+}
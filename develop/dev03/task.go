@@ -26,162 +26,156 @@
 package main
 
 import (
-	"flag"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
 	"os"
-	"reflect"
-	"sort"
-	"strconv"
-	"strings"
 )
 
-// Утилита sort сортирует строки текстового файла
+// Утилита sort сортирует строки текстовых файлов (или стандартного ввода).
 // По умолчанию сортировка осуществляется по всей строке,
-// при желании можно разбить каждую строку по разделителю на столбцы и отсортировать строки по выбранному столбцу
+// при желании можно разбить каждую строку на поля и отсортировать строки по нескольким ключам (см. keySpec).
+// Файл не читается в память целиком: большие файлы сортируются по кускам через временные файлы (см. sorter)
 func main() {
-	sortByColumn := flag.Int("k", -1, "Указание колонки для сортировки") // По умолчанию сортируем по всей строке
-	sortByNumbers := flag.Bool("n", false, "Сортировать по числовому значению")
-	sortReverse := flag.Bool("r", false, "Сортировать в обратном порядке")
-	noDuplicates := flag.Bool("u", false, "Не выводить повторяющиеся строки")
-	ignoreEndSpace := flag.Bool("b", false, "Игнорировать хвостовые пробелы")
-	checkSort := flag.Bool("c", false, "Проверять отсортированы ли данные")
-	flag.Parse()
-
-	fileName := os.Args[len(os.Args)-1] // Имя файла будет последним аргументом
-	path, err := os.Getwd()             // Получаем текущую рабочую директорию
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	data, err := ioutil.ReadFile(path + "/" + fileName) // Читаем содержимое файла
-	if err != nil {
-		log.Fatal(err)
-	}
-	dataStr := string(data)
-	result := Sort(dataStr, *sortByColumn, *sortByNumbers, *sortReverse, *noDuplicates, *ignoreEndSpace, *checkSort)
-
-	print(result)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-// Sort принимает в качестве аргументов: содержимое файла в виде строкового значения, набор "конфигурационных" переменных,
-// значения которых установлены с помощью флагов
-func Sort(data string, sortByColumn int, sortByNumbers, sortReverse, noDuplicates, ignoreEndSpace, checkSort bool) []string {
-	if checkSort { // Если checkSort == true, проверяем, отсортированы ли данные
-		result := strings.Split(data, "\n")    // Разделяем исходное строковое значение по "\n", наполняем слайс строк
-		sorted := strings.Split(data, "\n")    // Делаем то же самое, только это слайс мы отсортируем
-		sort.Strings(sorted)                   // Один из одинаковых слайсов отсортируем
-		if reflect.DeepEqual(result, sorted) { // Сравним отсортированный с таким же, но несортированным, и если они равны, то:
-			fmt.Println("sorted")
-		} else { // Иначе:
-			fmt.Println("unsorted")
-		}
-		return nil
+const usage = `Утилита sort. Использование: ./[название_исполняемого_файла] [опции] [ФАЙЛ...]
+Файлы сортируются вместе, как один поток; без файлов (и вместо файла "-") читается стандартный ввод. Короткие флаги можно объединять: -nr
+Доступные опции:
+  -k, --key=POS1[,POS2]   сортировать по ключу от POS1 до POS2 (по умолчанию - до конца строки);
+                          POS - это F[.C][МОДИФИКАТОРЫ]: номер поля F и символа C в нём, с единицы;
+                          модификаторы b, d, f, g, h, M, n, r, V действуют только на этот ключ; -k можно повторять
+  -t, --field-separator=С поля разделяются символом С, а не переходом от пробелов к непробельным символам
+  -n, --numeric-sort      сортировать по числовому значению (точно, без ограничения длины числа)
+  -g, --general-numeric-sort  сортировать по значению числа с плавающей точкой (1e3, inf, nan)
+  -h, --human-numeric-sort  сортировать по размерам с суффиксами (2K, 1G)
+  -M, --month-sort        сортировать по названию месяца, английскому или русскому (Jan, января, янв.)
+  -V, --version-sort      сортировать по номерам версий (file2 < file10, 1.0~rc1 < 1.0)
+  -r, --reverse           сортировать в обратном порядке
+  -b, --ignore-leading-blanks  игнорировать пробелы в начале ключа
+  -f, --ignore-case       не различать строчные и прописные буквы
+  -d, --dictionary-order  сравнивать только буквы (любого алфавита), цифры и пробелы
+      --locale=ЯЗЫК       сравнивать текст по правилам языка (ru, en, ru_RU.UTF-8...), а не побайтно:
+                          ё рядом с е, прописные рядом со строчными; C и POSIX - побайтно (по умолчанию)
+  -s, --stable            не сравнивать строки целиком, если ключи равны
+  -u, --unique            из строк с равными ключами выводить только первую
+  -c, --check[=diagnose-first]  проверить, отсортированы ли данные, и сообщить о первом нарушении порядка;
+                          код возврата 1, если не отсортированы (при -u равные строки тоже нарушение)
+  -C, --check=quiet       то же, но без сообщения (--check=silent - то же самое)
+  -m, --merge             слить уже отсортированные файлы, не сортируя их заново
+  -o, --output=ФАЙЛ       записать результат в ФАЙЛ, а не на стандартный вывод; ФАЙЛ может быть и одним из входных
+  -z, --zero-terminated   записи разделяются нулевым байтом, а не переводом строки
+  -S, --buffer-size=N     сколько памяти занимать под строки: число с суффиксом b, K, M, G, T (без суффикса - K)
+  -T, --temporary-directory=КАТАЛОГ  каталог для временных файлов (по умолчанию $TMPDIR или /tmp)
+      --parallel=N        во сколько потоков сортировать (по умолчанию - по числу процессоров, но не больше 8)
+      --help              показать помощь и выйти
+`
+
+var errCheckOperand = errors.New("при проверке можно указать только один файл")
+
+// Коды возврата, как у GNU sort
+const (
+	exitOK       = 0 // Отсортировано (или при -c/-C: уже было отсортировано)
+	exitDisorder = 1 // -c/-C: данные не отсортированы
+	exitTrouble  = 2 // Ошибка: неверные опции, недоступный файл
+)
+
+// run выполняет sort с аргументами args и возвращает код возврата.
+// Ввод-вывод передаётся параметрами, чтобы программу целиком можно было прогнать в тестах
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args)
+	if err == nil && opts.check != checkNone && len(opts.files) > 1 {
+		err = fmt.Errorf("%w: %q", errCheckOperand, opts.files[1])
 	}
-	if ignoreEndSpace {
-		data = strings.TrimSpace(data) // Режем хвостовые пробелы
+	if err != nil {
+		fmt.Fprintf(stderr, "sort: %v\n%s", err, usage)
+		return exitTrouble
 	}
-	result := strings.Split(data, "\n") // Разделяем содержимое файла по "\n", на отдельные строки (т. к. именно их мы и будем сортировать), наполняем ими слайс
-	if sortByColumn >= -1 {
-		result = columnSort(result, sortByColumn)
+	if opts.help {
+		fmt.Fprint(stdout, usage)
+		return exitOK
 	}
 
-	if noDuplicates { // Не выводить повторяющиеся строки
-		un := map[string]struct{}{}
-		for _, r := range result {
-			un[r] = struct{}{}
-		}
-		result = make([]string, 0, len(un))
-		for k := range un {
-			result = append(result, k)
-		}
+	s := &sorter{
+		compare:   opts.comparator().compare,
+		unique:    opts.unique,
+		budget:    opts.bufferSize,
+		tempDir:   opts.tempDir,
+		parallel:  opts.parallel,
+		batchSize: defaultBatchSize,
+		delim:     opts.delim(),
 	}
-	if sortByNumbers {
-		nums := make([]int, 0, len(result)) // Создаём слайс целых чисел с cap равной количеству строк для сортировки
-		if checkSort {                      // Проверяет, отсортировано ли содержимое файла
-			if !sort.IntsAreSorted(nums) {
-				return []string{"Need to sort"}
-			} else {
-				return []string{"No need to sort"}
-			}
-		}
-		for k := range result { // Перебираем индексы строк
-			n, err := strconv.Atoi(result[k])
-			if err != nil {
-				fmt.Println("error sorting by number:", err)
-				os.Exit(1)
-			}
-			nums = append(nums, n)
-		}
-		if sortReverse {
-			sort.Sort(sort.Reverse(sort.IntSlice(nums)))
-		} else {
-			sort.Ints(nums)
-		}
-
-		result = make([]string, 0, len(nums))
-		for _, n := range nums {
 
-			result = append(result, strconv.Itoa(n))
+	if opts.check != checkNone { // Проверяем, отсортированы ли данные, тем же сравнением, что и при сортировке
+		name := "-"
+		if len(opts.files) == 1 {
+			name = opts.files[0]
 		}
+		in := newInputs(opts.files, stdin, s.delim)
+		defer in.close()
+		num, line, err := checkSorted(in, s.compare, opts.unique)
+		switch {
+		case err != nil:
+			fmt.Fprintf(stderr, "sort: %v\n", err)
+			return exitTrouble
+		case num == 0:
+			return exitOK
+		case opts.check == checkDiagnose: // Сообщение как у GNU sort: его разбирают скрипты. Завершается оно разделителем записей
+			fmt.Fprintf(stderr, "sort: %s:%d: disorder: %s%c", name, num, line, s.delim)
+		}
+		return exitDisorder
 	}
 
-	if sortReverse {
-		SortReverse(result)
-	}
-
-	return result
-}
-
-// columnSort сортирует строки по какому-то столбцу. Если в строке меньше столбцов она сдвигается
-// вниз и сортируется по последнему слову в строке
-func columnSort(data []string, ColumnNum int) []string {
-	if ColumnNum == -1 { // Номер столбца по которому сортируем. По умолчанию - вся строка (до перевода строки)
-		sort.Strings(data)
-		return data
+	var out io.Writer = stdout
+	var file *outputFile
+	if opts.output != "" {
+		file = &outputFile{path: opts.output}
+		out = file
 	}
-
-	sort.Slice(data, func(i, j int) bool {
-		lhs := strings.Split(data[i], " ")
-		rhs := strings.Split(data[j], " ")
-		if len(lhs) <= ColumnNum || len(rhs) <= ColumnNum { // Если количество столбцов в двух соседних строках меньше, чем н
-			return lhs[0] < rhs[0] // Сортируем по возрастанию слова в столбце
+	if opts.merge {
+		names, copies := opts.files, []*chunkFile(nil)
+		if file != nil { // Слияние пишет, не дочитав ввод, поэтому файл вывода среди входных заменяем копией
+			names, copies, err = s.copyOverlapping(names, opts.output)
+			defer removeRuns(copies)
 		}
-		return strings.Split(data[i], " ")[ColumnNum] <
-			strings.Split(data[j], " ")[ColumnNum]
-	})
-	return data
-
-}
-
-// SortByNumbers сортирует строки
-func SortByNumbers(text []string, row int) []string {
-	var count int
-	for i, v := range text { // сдвигаем вниз все строки, которые не числа
-		words := strings.Split(v, " ")
-		if len(words) < row {
-			temp := text[i]
-			for k := i; k < len(text)-1; k++ {
-				text[k] = text[k+1]
-			}
-			text[len(text)-1] = temp
-		} else {
-			count++ // счетчик строк, которые числа
+		if err == nil {
+			err = s.mergeInputs(names, stdin, out)
 		}
+	} else {
+		err = s.sort(newInputs(opts.files, stdin, s.delim), out)
 	}
-	return nil
-}
-
-func SortReverse(data []string) []string {
-	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
-		data[i], data[j] = data[j], data[i]
+	if err == nil && file != nil {
+		err = file.Close()
 	}
-	return data
+	if err != nil {
+		fmt.Fprintf(stderr, "sort: %v\n", err)
+		return exitTrouble
+	}
+	return exitOK
 }
 
-func print(text []string) {
-	for _, v := range text {
-		fmt.Println(v)
+// checkSorted проверяет, что записи in уже идут в порядке compare (при unique - строго по возрастанию, без равных).
+// Возвращает номер и текст первой записи не на своём месте, 0 - всё отсортировано. Поток не загружается в память
+func checkSorted(in *inputs, compare func(a, b string) int, unique bool) (int, string, error) {
+	prev, err := in.next()
+	if err == io.EOF {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	for num := 2; ; num++ {
+		line, err := in.next()
+		if err == io.EOF {
+			return 0, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if c := compare(prev, line); c > 0 || (unique && c == 0) {
+			return num, line, nil
+		}
+		prev = line
 	}
 }
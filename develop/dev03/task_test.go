@@ -1 +1,361 @@
 package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// randomLines возвращает n случайных строк с повторами: повторы проверяют устойчивость и -u
+func randomLines(n int) []string {
+	rnd := rand.New(rand.NewSource(1))
+	words := []string{"мышь", "кот", "Apple", "банан", "42", "7", "", "дом солнце", "zebra", "ёж"}
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s %d", words[rnd.Intn(len(words))], rnd.Intn(50))
+	}
+	return lines
+}
+
+// keyCompare возвращает устойчивое (без сравнения строк целиком) сравнение по ключу -k spec
+func keyCompare(t *testing.T, spec string) func(a, b string) int {
+	key, err := parseKeySpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newComparator([]keySpec{key}, keyOptions{}, 0, false, true, false, nil).compare
+}
+
+func TestExternalSort(t *testing.T) {
+	lines := randomLines(500)
+	input := strings.Join(lines, "\n") + "\n"
+
+	tests := []struct {
+		name     string
+		budget   int64
+		parallel int
+		unique   bool
+		compare  func(a, b string) int
+	}{
+		{name: "в памяти", budget: defaultBufferSize, parallel: 1},
+		{name: "в памяти параллельно", budget: defaultBufferSize, parallel: 3},
+		{name: "куски по строке, несколько проходов слияния", budget: 1, parallel: 1},
+		{name: "куски по 1К параллельно", budget: 1 << 10, parallel: 4},
+		{name: "без повторов", budget: 1 << 10, parallel: 2, unique: true},
+		{name: "по числу во втором столбце, в обратном порядке", budget: 512, parallel: 2, compare: keyCompare(t, "2,2nr")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compare := tt.compare
+			if compare == nil {
+				compare = strings.Compare
+			}
+			want := append([]string(nil), lines...)
+			sort.SliceStable(want, func(i, j int) bool { return compare(want[i], want[j]) < 0 })
+			if tt.unique {
+				uniq := want[:1]
+				for _, line := range want[1:] {
+					if line != uniq[len(uniq)-1] {
+						uniq = append(uniq, line)
+					}
+				}
+				want = uniq
+			}
+
+			tempDir := t.TempDir()
+			s := &sorter{compare: compare, unique: tt.unique, budget: tt.budget, tempDir: tempDir, parallel: tt.parallel,
+				batchSize: defaultBatchSize, delim: '\n'}
+			var out bytes.Buffer
+			if err := s.sort(newInputs(nil, strings.NewReader(input), '\n'), &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("результат отличается от sort.SliceStable:\n%s", out.String())
+			}
+			if files, _ := ioutil.ReadDir(tempDir); len(files) != 0 {
+				t.Errorf("остались временные файлы: %d", len(files))
+			}
+		})
+	}
+}
+
+func TestParseBufferSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		err   error
+	}{
+		{value: "10", want: 10 << 10},
+		{value: "100b", want: 100},
+		{value: "2K", want: 2 << 10},
+		{value: "64M", want: 64 << 20},
+		{value: "1G", want: 1 << 30},
+		{value: "0", err: errBufferSize},
+		{value: "M", err: errBufferSize},
+		{value: "10Q", err: errBufferSize},
+	}
+	for _, tt := range tests {
+		got, err := parseBufferSize(tt.value)
+		if got != tt.want || err != tt.err {
+			t.Errorf("parseBufferSize(%q) = %d, %v; ожидалось %d, %v", tt.value, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRunWithTempFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-S", "1b", "-T", tempDir, "--parallel", "2"}, strings.NewReader("в\nб\nг\nа"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("код возврата %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "а\nб\nв\nг\n" {
+		t.Errorf("вывод %q", stdout.String())
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string // Входные файлы во временном каталоге
+		args   []string          // "@" заменяется путём к временному каталогу
+		stdin  string
+		output string // Файл, в котором ждём результат, "" - стандартный вывод
+		want   string
+	}{
+		{
+			name:   "-o в один из входных файлов",
+			files:  map[string]string{"a": "3\n1", "b": "2\n4\n"},
+			args:   []string{"-o", "@/a", "@/a", "@/b"},
+			output: "a",
+			want:   "1\n2\n3\n4\n",
+		},
+		{
+			name:   "-m с -o в один из входных файлов",
+			files:  map[string]string{"a": "1\n3\n", "b": "2\n4\n"},
+			args:   []string{"-m", "-o@/b", "@/b", "@/a"},
+			output: "b",
+			want:   "1\n2\n3\n4\n",
+		},
+		{
+			name:  "-m с повторами и стандартным вводом",
+			files: map[string]string{"a": "1\n3"},
+			args:  []string{"-mu", "@/a", "-", "@/a"},
+			stdin: "2\n3\n5\n",
+			want:  "1\n2\n3\n5\n",
+		},
+		{
+			name:   "-o в новый файл при пустом вводе",
+			args:   []string{"-o", "@/out"},
+			output: "out",
+			want:   "",
+		},
+		{
+			name:  "-z с переводами строк внутри записей, через временные файлы",
+			args:  []string{"-z", "-S1b", "-T@", "-k2"},
+			stdin: "x\nб\x00y\nа\x00z в",
+			want:  "y\nа\x00x\nб\x00z в\x00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = strings.ReplaceAll(arg, "@", dir)
+			}
+			var stdout, stderr bytes.Buffer
+			if code := run(args, strings.NewReader(tt.stdin), &stdout, &stderr); code != 0 {
+				t.Fatalf("код возврата %d: %s", code, stderr.String())
+			}
+			got := stdout.String()
+			if tt.output != "" {
+				data, err := ioutil.ReadFile(filepath.Join(dir, tt.output))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(data)
+			}
+			if got != tt.want {
+				t.Errorf("sort %v: %q, ожидалось %q", args, got, tt.want)
+			}
+			entries, _ := ioutil.ReadDir(dir)
+			for _, e := range entries {
+				if _, ok := tt.files[e.Name()]; !ok && e.Name() != tt.output {
+					t.Errorf("остался временный файл %s", e.Name())
+				}
+			}
+		})
+	}
+}
+
+// TestGNUConformance прогоняет сценарии из testdata/cases.txt и сравнивает вывод с записанным выводом GNU sort
+func TestGNUConformance(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "cases.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		name, args := fields[0], strings.Fields(fields[2])
+		code, err := strconv.Atoi(fields[1])
+		if err != nil {
+			t.Fatalf("%s: неверный код возврата %q", name, fields[1])
+		}
+		t.Run(name, func(t *testing.T) {
+			want, err := ioutil.ReadFile(filepath.Join("testdata", "gnu", name+".out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer // Как и в record.sh, stdout и stderr пишутся вместе
+			if got := run(args, strings.NewReader(""), &out, &out); got != code {
+				t.Fatalf("sort %v: код возврата %d, ожидалось %d (вывод: %s)", args, got, code, out.String())
+			}
+			if out.String() != string(want) {
+				t.Errorf("sort %v:\nполучено:\n%s\nожидалось:\n%s", args, out.String(), want)
+			}
+		})
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseKeySpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want keySpec
+		err  error
+	}{
+		{spec: "2", want: keySpec{startField: 2, startChar: 1}},
+		{spec: "2,2", want: keySpec{startField: 2, startChar: 1, endField: 2}},
+		{spec: "1.3,1.5", want: keySpec{startField: 1, startChar: 3, endField: 1, endChar: 5}},
+		{spec: "2b,3nr", want: keySpec{startField: 2, startChar: 1, endField: 3,
+			opts: keyOptions{kind: orderNumeric, reverse: true, skipStart: true, hasOptions: true}}},
+		{spec: "1,1b", want: keySpec{startField: 1, startChar: 1, endField: 1, opts: keyOptions{skipEnd: true, hasOptions: true}}},
+		{spec: "3hf", want: keySpec{startField: 3, startChar: 1, opts: keyOptions{kind: orderHuman, foldCase: true, hasOptions: true}}},
+		{spec: "0", err: errKeyZero},
+		{spec: "1.0", err: errKeySpec},
+		{spec: "x", err: errKeySpec},
+		{spec: "1,", err: nil, want: keySpec{startField: 1, startChar: 1}},
+		{spec: "1q", err: errKeySpec},
+		{spec: "1.", err: errKeySpec},
+	}
+	for _, tt := range tests {
+		got, err := parseKeySpec(tt.spec)
+		if !errors.Is(err, tt.err) || (tt.err == nil && got != tt.want) {
+			t.Errorf("parseKeySpec(%q) = %+v, %v; ожидалось %+v, %v", tt.spec, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		kind orderKind
+		a, b string
+		want int // Знак результата
+	}{
+		{kind: orderMonth, a: "января", b: "февраль", want: -1},
+		{kind: orderMonth, a: "  Мая", b: "май", want: 0},
+		{kind: orderMonth, a: "март", b: "мая", want: -1},
+		{kind: orderMonth, a: "дек.", b: "Nov", want: 1},
+		{kind: orderMonth, a: "сентябрь", b: "September", want: 0},
+		{kind: orderMonth, a: "мышь", b: "янв", want: -1},
+		{kind: orderMonth, a: "ма", b: "Jan", want: -1},
+		{kind: orderNumeric, a: "100000000000000000000001", b: "100000000000000000000000", want: 1},
+		{kind: orderNumeric, a: "-0", b: "0.000", want: 0},
+		{kind: orderNumeric, a: "-1.5", b: "-1.25", want: -1},
+		{kind: orderNumeric, a: "абв", b: "0", want: 0},
+		{kind: orderHuman, a: "1023K", b: "1M", want: -1},
+		{kind: orderHuman, a: "-1G", b: "5", want: -1},
+		{kind: orderGeneral, a: "1e-3", b: "0.01", want: -1},
+		{kind: orderGeneral, a: "nan", b: "-inf", want: -1},
+		{kind: orderVersion, a: "file2", b: "file10", want: -1},
+		{kind: orderVersion, a: "1.0~rc1", b: "1.0", want: -1},
+		{kind: orderVersion, a: "1.2.tar.gz", b: "1.10.tar.gz", want: -1},
+		{kind: orderVersion, a: "v010", b: "v10", want: 0},
+	}
+	sign := func(n int) int {
+		switch {
+		case n < 0:
+			return -1
+		case n > 0:
+			return 1
+		}
+		return 0
+	}
+	for _, tt := range tests {
+		if got := sign(compareKeys(tt.a, tt.b, &keyOptions{kind: tt.kind}, nil)); got != tt.want {
+			t.Errorf("compareKeys(%q, %q, вид %d) = %d, ожидалось %d", tt.a, tt.b, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestRunKeepsOriginalLines(t *testing.T) {
+	input := "10 мышей\nмного\n2 кота\n-3,5 градуса\n  1 дом\n"
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-n"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("код возврата %d: %s", code, stderr.String())
+	}
+	want := "-3,5 градуса\nмного\n  1 дом\n2 кота\n10 мышей\n"
+	if stdout.String() != want {
+		t.Errorf("вывод %q, ожидался %q", stdout.String(), want)
+	}
+}
+
+func TestRunLocale(t *testing.T) {
+	words := "ёж\nЕль\nель\nЯблоко\napple\nZebra\nжук\nежевика\nЁлка\nяблоко\nApple\nе-мейл\nемель\n"
+	tests := []struct {
+		args  []string
+		input string
+		want  string // Строки через пробел
+		code  int
+	}{
+		{args: nil, input: words, // Побайтно: латиница раньше кириллицы, прописные раньше строчных, ё после я
+			want: "Apple Zebra apple Ёлка Ель Яблоко е-мейл ежевика ель емель жук яблоко ёж"},
+		{args: []string{"--locale=ru"}, input: words,
+			want: "apple Apple Zebra е-мейл ёж ежевика Ёлка ель Ель емель жук яблоко Яблоко"},
+		{args: []string{"--locale", "ru_RU.UTF-8", "-r"}, input: words,
+			want: "Яблоко яблоко жук емель Ель ель Ёлка ежевика ёж е-мейл Zebra Apple apple"},
+		{args: []string{"--locale=ru", "-d"}, input: words, // Дефис не учитывается: "е-мейл" рядом с "емель"
+			want: "apple Apple Zebra ёж ежевика Ёлка ель Ель е-мейл емель жук яблоко Яблоко"},
+		{args: []string{"--locale=ru", "-fu"}, input: words, // Слова, отличающиеся только регистром, - повторы: остаётся первое
+			want: "apple Zebra е-мейл ёж ежевика Ёлка Ель емель жук Яблоко"},
+		{args: []string{"--locale=en", "-k2,2df", "-k1,1n"}, input: "3 «Ёлка»\n1 Ель\n2 ёлка\n4 (ель)\n",
+			want: "2 ёлка 3 «Ёлка» 1 Ель 4 (ель)"},
+		{args: []string{"-d"}, input: "«б»\nа\n(в)\n", want: "а «б» (в)"}, // -d оставляет и кириллицу
+		{args: []string{"--locale=C"}, input: "б\nЯ\n", want: "Я б"},
+		{args: []string{"--locale=xx-bogus"}, code: exitTrouble},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.input), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("sort %v: код возврата %d, ожидался %d: %s", tt.args, code, tt.code, stderr.String())
+			continue
+		}
+		if got := strings.Join(strings.Fields(strings.ReplaceAll(stdout.String(), "\n", " ")), " "); code == 0 && got != tt.want {
+			t.Errorf("sort %v:\nполучено  %s\nожидалось %s", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+#!/bin/sh
+# Перезаписывает эталонный вывод в gnu/ настоящим GNU sort.
+# Запускается из любого каталога: testdata/record.sh. Пути в аргументах - относительно dev03, как и в тестах
+cd "$(dirname "$0")/.." || exit 2
+export LC_ALL=C
+grep -v '^#' testdata/cases.txt | while IFS="$(printf '\t')" read -r name code args; do
+	# shellcheck disable=SC2086 # аргументы намеренно разбиваются по пробелам
+	sort $args < /dev/null > "testdata/gnu/$name.out" 2>&1 # Стандартный ввод пустой, как в тестах
+	status=$?
+	if [ "$status" != "$code" ]; then
+		echo "$name: GNU sort вернул $status, в cases.txt указано $code" >&2
+	fi
+done
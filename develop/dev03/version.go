@@ -0,0 +1,113 @@
+package main
+
+// compareVersions сравнивает строки как номера версий (sort -V), по алгоритму filevercmp из GNU coreutils:
+// числа внутри текста сравниваются по значению (file2 < file10), "~" идёт раньше всего, даже конца строки
+// (1.0~rc1 < 1.0), а суффиксы вида ".tar.gz" учитываются, только если без них строки равны
+func compareVersions(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	case b == "":
+		return 1
+	}
+	// "." раньше всего, затем "..", затем прочие имена с точкой в начале, затем всё остальное
+	if a[0] == '.' || b[0] == '.' {
+		if a[0] != b[0] {
+			if a[0] == '.' {
+				return -1
+			}
+			return 1
+		}
+		for _, special := range []string{".", ".."} {
+			if a == special {
+				return -1
+			}
+			if b == special {
+				return 1
+			}
+		}
+	}
+
+	aPrefix, bPrefix := filePrefixLen(a), filePrefixLen(b)
+	diff := compareVersionParts(a[:aPrefix], b[:bPrefix])
+	if diff != 0 || (aPrefix == len(a) && bPrefix == len(b)) {
+		return diff
+	}
+	return compareVersionParts(a, b)
+}
+
+// filePrefixLen возвращает длину s без суффикса вида (\.[A-Za-z~][A-Za-z0-9~]*)*
+func filePrefixLen(s string) int {
+	prefix := 0
+	for i := 0; i < len(s); {
+		i++
+		prefix = i
+		for i+1 < len(s) && s[i] == '.' && (isAlpha(s[i+1]) || s[i+1] == '~') {
+			for i += 2; i < len(s) && (isAlpha(s[i]) || isDigit(s[i]) || s[i] == '~'); i++ {
+			}
+		}
+	}
+	return prefix
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// versionOrder - вес символа в нечисловой части версии: "~" меньше конца строки, буквы меньше прочих символов
+func versionOrder(s string, i int) int {
+	switch {
+	case i >= len(s), isDigit(s[i]):
+		return 0
+	case isAlpha(s[i]):
+		return int(s[i])
+	case s[i] == '~':
+		return -1
+	}
+	return int(s[i]) + 256
+}
+
+// compareVersionParts - verrevcmp из dpkg: чередует сравнение нечисловых частей посимвольно и числовых по значению
+func compareVersionParts(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ca, cb := versionOrder(a, i), versionOrder(b, j)
+			if ca != cb {
+				return ca - cb
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) { // Число в a длиннее - оно больше
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
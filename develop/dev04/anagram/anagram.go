@@ -0,0 +1,182 @@
+// Package anagram группирует слова словаря в множества анаграмм.
+// Словарь читается потоком и целиком в памяти не держится: при превышении лимита памяти
+// промежуточные данные сортируются по частям во временных файлах (внешняя сортировка)
+package anagram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultMemoryLimit = 64 << 20 // Сколько памяти занимать под слова, если в Options не указано
+	defaultMinSize     = 2        // Множества из одного слова в результат не попадают
+	sep                = "\x00"   // Разделитель полей во временных записях: в словах его не бывает
+)
+
+// Options - настройки поиска. Нулевое значение - настройки по умолчанию
+type Options struct {
+	MinSize     int        // Сколько разных слов должно быть в множестве, по умолчанию 2
+	MemoryLimit int64      // Сколько байтов памяти занимать под слова, по умолчанию 64 МиБ
+	TempDir     string     // Где создавать временные файлы, "" - системный каталог
+	Key         KeyOptions // Какие слова считать составленными из одних и тех же букв
+}
+
+func (o Options) withDefaults() Options {
+	if o.MinSize <= 0 {
+		o.MinSize = defaultMinSize
+	}
+	if o.MemoryLimit <= 0 {
+		o.MemoryLimit = defaultMemoryLimit
+	}
+	return o
+}
+
+// Group - множество анаграмм
+type Group struct {
+	Key   string   `json:"key"`   // Первое встретившееся в словаре слово множества, как оно записано в словаре
+	Words []string `json:"words"` // Разные слова множества в нижнем регистре, по возрастанию.
+	// Слова, которые с учётом Options.Key совпадают ("ёлка" и "елка" при FoldYo), - одно слово: остаётся первое из словаря
+}
+
+// Find читает словарь из r (по слову на строку, пустые строки и пробелы по краям не учитываются)
+// и передаёт в emit множества анаграмм в порядке первого появления их слов в словаре.
+// Если emit вернёт ошибку, поиск прекращается с этой ошибкой
+func Find(r io.Reader, opts Options, emit func(Group) error) error {
+	opts = opts.withDefaults()
+	// Память делится между двумя сортировками: по ключу анаграммы (чтобы собрать множества)
+	// и по номеру первого слова (чтобы выдать их в порядке словаря)
+	bySignature := &recordSorter{budget: opts.MemoryLimit / 2, dir: opts.TempDir}
+	defer bySignature.discard()
+	if err := readWords(r, bySignature, opts.Key); err != nil {
+		return err
+	}
+
+	byFirst := &recordSorter{budget: opts.MemoryLimit / 2, dir: opts.TempDir}
+	defer byFirst.discard()
+	if err := collectGroups(bySignature, byFirst, opts.MinSize, opts.Key); err != nil {
+		return err
+	}
+
+	groups, err := byFirst.sorted()
+	if err != nil {
+		return err
+	}
+	defer groups.close()
+	for {
+		rec, err := groups.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fields := strings.SplitN(rec, sep, 3) // Номер первого слова, ключ, слова
+		if err := emit(Group{Key: fields[1], Words: strings.Split(fields[2], sep)}); err != nil {
+			return err
+		}
+	}
+}
+
+// readWords добавляет в s записи "ключ анаграммы, номер слова, слово".
+// Номер записывается шестнадцатеричным числом фиксированной ширины, чтобы записи с одним ключом
+// упорядочивались по нему обычным сравнением строк
+func readWords(r io.Reader, s *recordSorter, key KeyOptions) error {
+	br := bufio.NewReaderSize(r, 64<<10)
+	for num := 0; ; num++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		word := strings.TrimSpace(line)
+		if sig := key.Key(word); sig != "" && !strings.Contains(word, sep) { // Слово из одних знаков препинания при IgnorePunct пропускается
+			if err := s.add(sig + sep + fmt.Sprintf("%016x", num) + sep + word); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// collectGroups читает записи из bySignature (слова с одним ключом идут подряд, первым - встретившееся раньше всех)
+// и добавляет в byFirst записи "номер первого слова, первое слово, слова множества" для множеств не меньше minSize.
+// Слова, одинаковые после нормализации key, считаются одним словом
+func collectGroups(bySignature, byFirst *recordSorter, minSize int, key KeyOptions) error {
+	recs, err := bySignature.sorted()
+	if err != nil {
+		return err
+	}
+	defer recs.close()
+
+	var (
+		signature, first, groupKey string
+		words                      []string
+		seen                       map[string]bool // Нормализованные слова текущего множества
+		started                    bool            // Прочитана хотя бы одна запись
+	)
+	flush := func() error {
+		words = uniqueSorted(words)
+		if len(words) < minSize {
+			return nil
+		}
+		return byFirst.add(first + sep + groupKey + sep + strings.Join(words, sep))
+	}
+	for {
+		rec, err := recs.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fields := strings.SplitN(rec, sep, 3)   // Ключ анаграммы, номер слова, слово
+		if !started || fields[0] != signature { // Началось следующее множество
+			if started {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			signature, first, groupKey, words, seen, started = fields[0], fields[1], fields[2], words[:0], map[string]bool{}, true
+		}
+		if normalized := key.normalize(fields[2]); !seen[normalized] {
+			seen[normalized] = true
+			words = append(words, strings.ToLower(fields[2]))
+		}
+	}
+	if !started {
+		return nil
+	}
+	return flush()
+}
+
+// uniqueSorted сортирует слова и убирает повторы
+func uniqueSorted(words []string) []string {
+	sort.Strings(words)
+	unique := words[:0]
+	for _, w := range words {
+		if len(unique) == 0 || w != unique[len(unique)-1] {
+			unique = append(unique, w)
+		}
+	}
+	return unique
+}
+
+// FindWords - то же, что Find, для словаря в памяти, с настройками по умолчанию, изменёнными opts.
+// Возвращает множества по ключам
+func FindWords(words []string, opts ...Option) (map[string][]string, error) {
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string][]string)
+	err = Find(strings.NewReader(strings.Join(words, "\n")), o, func(g Group) error {
+		res[g.Key] = g.Words
+		return nil
+	})
+	return res, err
+}
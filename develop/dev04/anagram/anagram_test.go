@@ -0,0 +1,127 @@
+package anagram
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{"пятка", "акптя"},
+		{"ТЯПКА", "акптя"},
+		{"листок", "иклост"},
+		{"колокол", "ккллооо"},
+		{"Ёж", "жё"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.out, Signature(tc.in), tc.in)
+	}
+}
+
+// collect возвращает множества в порядке выдачи
+func collect(t *testing.T, dict string, opts Options) []Group {
+	var groups []Group
+	err := Find(strings.NewReader(dict), opts, func(g Group) error {
+		groups = append(groups, g)
+		return nil
+	})
+	require.NoError(t, err)
+	return groups
+}
+
+func TestFind(t *testing.T) {
+	dict := "  листок\nПЯТка\n\nпятак\nкот\nслиток\nтяпка\nток\nСТОЛИК\nлисток\nпятка\nволк\nкто\nмышь"
+	testCases := []struct {
+		name string
+		opts Options
+		want []Group
+	}{
+		{
+			name: "по умолчанию",
+			want: []Group{
+				{Key: "листок", Words: []string{"листок", "слиток", "столик"}},
+				{Key: "ПЯТка", Words: []string{"пятак", "пятка", "тяпка"}},
+				{Key: "кот", Words: []string{"кот", "кто", "ток"}},
+			},
+		},
+		{
+			name: "повторы не увеличивают множество",
+			opts: Options{MinSize: 4},
+		},
+		{
+			name: "множества из одного слова",
+			opts: Options{MinSize: 1},
+			want: []Group{
+				{Key: "листок", Words: []string{"листок", "слиток", "столик"}},
+				{Key: "ПЯТка", Words: []string{"пятак", "пятка", "тяпка"}},
+				{Key: "кот", Words: []string{"кот", "кто", "ток"}},
+				{Key: "волк", Words: []string{"волк"}},
+				{Key: "мышь", Words: []string{"мышь"}},
+			},
+		},
+		{
+			name: "через временные файлы",
+			opts: Options{MemoryLimit: 1},
+			want: []Group{
+				{Key: "листок", Words: []string{"листок", "слиток", "столик"}},
+				{Key: "ПЯТка", Words: []string{"пятак", "пятка", "тяпка"}},
+				{Key: "кот", Words: []string{"кот", "кто", "ток"}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.TempDir = t.TempDir()
+			assert.Equal(t, tc.want, collect(t, dict, tc.opts))
+			files, err := ioutil.ReadDir(tc.opts.TempDir)
+			require.NoError(t, err)
+			assert.Empty(t, files, "остались временные файлы")
+		})
+	}
+}
+
+func TestFindLargeDictionary(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	letters := []rune("абвгдеж")
+	var dict strings.Builder
+	for i := 0; i < 20000; i++ {
+		word := make([]rune, 3+rnd.Intn(3))
+		for j := range word {
+			word[j] = letters[rnd.Intn(len(letters))]
+		}
+		fmt.Fprintln(&dict, string(word))
+	}
+	inMemory := collect(t, dict.String(), Options{})
+	require.NotEmpty(t, inMemory)
+	for _, limit := range []int64{1 << 10, 64 << 10} { // Сотни и единицы временных файлов
+		dir := t.TempDir()
+		assert.Equal(t, inMemory, collect(t, dict.String(), Options{MemoryLimit: limit, TempDir: dir}), "лимит %d", limit)
+		files, _ := ioutil.ReadDir(dir)
+		assert.Empty(t, files, "остались временные файлы")
+	}
+}
+
+func TestFindStopsOnEmitError(t *testing.T) {
+	errStop := errors.New("хватит")
+	dir := t.TempDir()
+	calls := 0
+	err := Find(strings.NewReader("кот\nток\nпятка\nтяпка\n"), Options{MemoryLimit: 1, TempDir: dir}, func(Group) error {
+		calls++
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 1, calls)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files, "остались временные файлы")
+}
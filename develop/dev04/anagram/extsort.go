@@ -0,0 +1,266 @@
+package anagram
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	recordOverhead = 32 // Сколько памяти сверх самих байтов уходит на запись: заголовок string и место в слайсе
+	mergeBatch     = 16 // Сколько временных файлов сливать за один проход, как --batch-size в GNU sort
+)
+
+// recordSorter - внешняя сортировка записей (строк без перевода строки) по байтам.
+// Записи копятся в памяти, пока не превысят бюджет, затем сортируются и сбрасываются во временный файл.
+// sorted сливает временные файлы и остаток в памяти в один упорядоченный поток
+type recordSorter struct {
+	budget int64  // Сколько памяти можно занять записями
+	dir    string // Где создавать временные файлы, "" - системный каталог
+	batch  int    // Сколько временных файлов сливать за раз (и держать открытыми), 0 - mergeBatch
+	buf    []string
+	used   int64
+	runs   []string // Пути временных файлов
+}
+
+// add добавляет запись, при превышении бюджета сбрасывая накопленное во временный файл
+func (s *recordSorter) add(rec string) error {
+	s.buf = append(s.buf, rec)
+	s.used += int64(len(rec)) + recordOverhead
+	if s.used >= s.budget {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill сортирует накопленные записи и записывает их во временный файл
+func (s *recordSorter) spill() error {
+	sort.Strings(s.buf)
+	f, err := ioutil.TempFile(s.dir, "anagram")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f.Name())
+	bw := bufio.NewWriterSize(f, 64<<10)
+	for _, rec := range s.buf {
+		bw.WriteString(rec)
+		bw.WriteByte('\n')
+	}
+	err = bw.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	s.buf, s.used = nil, 0 // Отпускаем память, а не только обнуляем длину
+	return err
+}
+
+// sorted возвращает все добавленные записи по возрастанию. После чтения (или при ошибке) нужно вызвать close
+func (s *recordSorter) sorted() (*recordMerger, error) {
+	sort.Strings(s.buf)
+	if err := s.reduceRuns(); err != nil {
+		s.discard()
+		return nil, err
+	}
+	m, err := mergeRuns(s.runs, s.buf)
+	if err != nil {
+		s.discard()
+		return nil, err
+	}
+	m.runs = s.runs // Теперь временные файлы удалит close
+	s.runs, s.buf = nil, nil
+	return m, nil
+}
+
+// reduceRuns сливает временные файлы группами по batch в новые временные файлы, пока их не останется не больше batch.
+// Так при любом числе файлов одновременно открыто не больше batch файлов и столько же буферов чтения
+func (s *recordSorter) reduceRuns() error {
+	batch := s.batch
+	if batch < 2 {
+		batch = mergeBatch
+	}
+	for len(s.runs) > batch {
+		var merged []string
+		for i := 0; i < len(s.runs); i += batch {
+			end := i + batch
+			if end > len(s.runs) {
+				end = len(s.runs)
+			}
+			path, err := s.mergeToTemp(s.runs[i:end])
+			if path != "" {
+				merged = append(merged, path)
+			}
+			removeRuns(s.runs[i:end])
+			if err != nil {
+				s.runs = append(merged, s.runs[end:]...) // Остальные удалит discard
+				return err
+			}
+		}
+		s.runs = merged
+	}
+	return nil
+}
+
+// mergeToTemp сливает группу временных файлов в новый временный файл и возвращает его путь
+func (s *recordSorter) mergeToTemp(runs []string) (string, error) {
+	f, err := ioutil.TempFile(s.dir, "anagram")
+	if err != nil {
+		return "", err
+	}
+	m, err := mergeRuns(runs, nil)
+	if err == nil {
+		bw := bufio.NewWriterSize(f, 64<<10)
+		var rec string
+		for rec, err = m.next(); err == nil; rec, err = m.next() {
+			bw.WriteString(rec)
+			bw.WriteByte('\n')
+		}
+		m.close()
+		if err == io.EOF {
+			err = bw.Flush()
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return f.Name(), err
+}
+
+// mergeRuns открывает временные файлы runs и сливает их с упорядоченными записями из памяти recs.
+// Сами файлы merger не удаляет, пока не заполнено поле runs
+func mergeRuns(runs, recs []string) (*recordMerger, error) {
+	m := &recordMerger{}
+	sources := []recordSource{&sliceSource{recs: recs}}
+	for _, path := range runs {
+		f, err := os.Open(path)
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		m.files = append(m.files, f)
+		sources = append(sources, &fileSource{br: bufio.NewReaderSize(f, 64<<10)})
+	}
+	if err := m.init(sources); err != nil {
+		m.close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// removeRuns удаляет временные файлы
+func removeRuns(runs []string) {
+	for _, path := range runs {
+		os.Remove(path)
+	}
+}
+
+// discard удаляет временные файлы, если до sorted дело не дошло
+func (s *recordSorter) discard() {
+	removeRuns(s.runs)
+	s.runs, s.buf = nil, nil
+}
+
+// recordSource - упорядоченный поток записей; io.EOF - записи кончились
+type recordSource interface {
+	next() (string, error)
+}
+
+// sliceSource - записи, оставшиеся в памяти
+type sliceSource struct {
+	recs []string
+}
+
+func (s *sliceSource) next() (string, error) {
+	if len(s.recs) == 0 {
+		return "", io.EOF
+	}
+	rec := s.recs[0]
+	s.recs = s.recs[1:]
+	return rec, nil
+}
+
+// fileSource - записи временного файла, по одной на строку
+type fileSource struct {
+	br *bufio.Reader
+}
+
+func (s *fileSource) next() (string, error) {
+	rec, err := s.br.ReadString('\n')
+	if err == io.EOF && rec != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(rec, "\n"), err
+}
+
+// mergeItem - текущая запись одного из источников
+type mergeItem struct {
+	rec    string
+	source recordSource
+}
+
+// mergeHeap - минимальная куча текущих записей всех источников
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].rec < h[j].rec }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// recordMerger сливает отсортированные источники (k-путевое слияние через кучу)
+type recordMerger struct {
+	heap  mergeHeap
+	files []*os.File
+	runs  []string
+}
+
+func (m *recordMerger) init(sources []recordSource) error {
+	for _, src := range sources {
+		rec, err := src.next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.heap = append(m.heap, mergeItem{rec: rec, source: src})
+	}
+	heap.Init(&m.heap)
+	return nil
+}
+
+// next возвращает следующую по порядку запись или io.EOF
+func (m *recordMerger) next() (string, error) {
+	if len(m.heap) == 0 {
+		return "", io.EOF
+	}
+	top := m.heap[0]
+	rec, err := top.source.next()
+	switch {
+	case err == io.EOF: // Источник исчерпан
+		heap.Pop(&m.heap)
+	case err != nil:
+		return "", err
+	default: // Заменяем вершину следующей записью того же источника
+		m.heap[0].rec = rec
+		heap.Fix(&m.heap, 0)
+	}
+	return top.rec, nil
+}
+
+// close закрывает и удаляет временные файлы
+func (m *recordMerger) close() {
+	for _, f := range m.files {
+		f.Close()
+	}
+	removeRuns(m.runs)
+	m.files, m.runs = nil, nil
+}
@@ -0,0 +1,40 @@
+package anagram
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordSorterMergesInBatches(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	dir := t.TempDir()
+	s := &recordSorter{budget: 10 * (recordOverhead + 8), dir: dir, batch: 3}
+	var expected []string
+	for i := 0; i < 500; i++ {
+		rec := fmt.Sprintf("%08d", rnd.Intn(1000000))
+		expected = append(expected, rec)
+		require.NoError(t, s.add(rec))
+	}
+	sort.Strings(expected)
+	require.Greater(t, len(s.runs), 3*3, "временных файлов должно хватить на несколько проходов слияния")
+
+	m, err := s.sorted()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(m.files), 3, "при окончательном слиянии открыто больше batch файлов")
+	var actual []string
+	for rec, err := m.next(); err != io.EOF; rec, err = m.next() {
+		require.NoError(t, err)
+		actual = append(actual, rec)
+	}
+	m.close()
+	assert.Equal(t, expected, actual)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files, "остались временные файлы")
+}
@@ -0,0 +1,325 @@
+package anagram
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// indexHeader - начало первой строки файла индекса: по нему узнаём формат и его версию.
+// Дальше в той же строке записаны настройки ключа, с которыми строился индекс
+const indexHeader = "anagram-index 1"
+
+var errIndexFormat = errors.New("неверный формат индекса")
+
+// Index - индекс словаря для запросов: слова, сгруппированные по ключу анаграммы (см. Signature).
+// Поиск анаграмм слова - одно обращение к map; поиск слов и фраз из набора букв перебирает ключи
+type Index struct {
+	words map[string][]string // Ключ анаграммы -> разные слова с этим ключом в нижнем регистре, по возрастанию
+	key   KeyOptions          // По каким правилам строятся ключи слов и запросов
+}
+
+// NewIndex возвращает пустой индекс, ключи в котором строятся по правилам key
+func NewIndex(key KeyOptions) *Index {
+	return &Index{words: make(map[string][]string), key: key}
+}
+
+// BuildIndex строит индекс по словарю (по слову на строку, пустые строки и пробелы по краям не учитываются)
+func BuildIndex(r io.Reader, key KeyOptions) (*Index, error) {
+	ix := NewIndex(key)
+	br := bufio.NewReaderSize(r, 64<<10)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		ix.Add(line)
+		if err == io.EOF {
+			return ix, nil
+		}
+	}
+}
+
+// Add добавляет слово в индекс. Повторное добавление (в том числе слова, совпадающего с уже добавленным
+// после нормализации) ничего не меняет.
+// Слова с табуляцией внутри не добавляются: табуляция разделяет слова в файле индекса
+func (ix *Index) Add(word string) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	sig := ix.key.Key(word)
+	if sig == "" || strings.ContainsRune(word, '\t') {
+		return
+	}
+	words := ix.words[sig]
+	normalized := ix.key.normalize(word)
+	for _, w := range words {
+		if ix.key.normalize(w) == normalized {
+			return
+		}
+	}
+	i := sort.SearchStrings(words, word)
+	words = append(words, "")
+	copy(words[i+1:], words[i:])
+	words[i] = word
+	ix.words[sig] = words
+}
+
+// Len возвращает количество слов в индексе
+func (ix *Index) Len() int {
+	n := 0
+	for _, words := range ix.words {
+		n += len(words)
+	}
+	return n
+}
+
+// Lookup возвращает все слова словаря из тех же букв, что и word (включая само word, если оно есть в словаре),
+// по возрастанию. Регистр не важен
+func (ix *Index) Lookup(word string) []string {
+	return append([]string(nil), ix.words[ix.key.Key(strings.TrimSpace(word))]...)
+}
+
+// signatures возвращает ключи индекса по возрастанию: так результаты поиска не зависят от порядка обхода map
+func (ix *Index) signatures() []string {
+	sigs := make([]string, 0, len(ix.words))
+	for sig := range ix.words {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	return sigs
+}
+
+// Save записывает индекс в w: заголовок, затем по строке на ключ - ключ и его слова через табуляцию.
+// Строки упорядочены по ключу, поэтому один и тот же словарь всегда даёт один и тот же файл
+func (ix *Index) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(indexHeader + " " + ix.key.String() + "\n")
+	for _, sig := range ix.signatures() {
+		bw.WriteString(sig)
+		for _, word := range ix.words[sig] {
+			bw.WriteByte('\t')
+			bw.WriteString(word)
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// LoadIndex читает индекс, записанный Save
+func LoadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	header, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	header = strings.TrimSuffix(header, "\n")
+	if header != indexHeader && !strings.HasPrefix(header, indexHeader+" ") {
+		return nil, fmt.Errorf("%w: нет заголовка %q", errIndexFormat, indexHeader)
+	}
+	key, err := parseKeyOptions(strings.TrimPrefix(header, indexHeader))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIndexFormat, err)
+	}
+	ix := NewIndex(key)
+	for num := 2; ; num++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			fields := strings.Split(line, "\t")
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%w: строка %d", errIndexFormat, num)
+			}
+			for _, word := range fields[1:] {
+				if key.Key(word) != fields[0] {
+					return nil, fmt.Errorf("%w: строка %d: слово %q не подходит к ключу %q", errIndexFormat, num, word, fields[0])
+				}
+			}
+			words := fields[1:]
+			sort.Strings(words) // Save пишет слова по возрастанию, но файл могли поправить руками
+			ix.words[fields[0]] = words
+		}
+		if err == io.EOF {
+			return ix, nil
+		}
+	}
+}
+
+// letterSet - набор букв запроса: сколько раз встречается каждая буква.
+// Буквы нумеруются по порядку появления в запросе, так что набор - это короткий вектор счётчиков
+type letterSet struct {
+	index map[rune]int // Буква -> номер в векторе
+	total []int        // Сколько каждой буквы в запросе
+}
+
+func newLetterSet(letters string, key KeyOptions) *letterSet {
+	ls := &letterSet{index: make(map[rune]int)}
+	for _, r := range key.Key(letters) {
+		if unicode.IsSpace(r) { // Пробелы в запросе фразы только разделяют слова
+			continue
+		}
+		i, ok := ls.index[r]
+		if !ok {
+			i = len(ls.total)
+			ls.index[r] = i
+			ls.total = append(ls.total, 0)
+		}
+		ls.total[i]++
+	}
+	return ls
+}
+
+// counts возвращает вектор счётчиков для ключа sig или false, если в запросе нет какой-то из его букв
+// или её там меньше, чем нужно
+func (ls *letterSet) counts(sig string) ([]int, bool) {
+	counts := make([]int, len(ls.total))
+	for _, r := range sig {
+		i, ok := ls.index[r]
+		if !ok {
+			return nil, false
+		}
+		counts[i]++
+		if counts[i] > ls.total[i] {
+			return nil, false
+		}
+	}
+	return counts, true
+}
+
+// Words возвращает слова словаря, которые можно составить из букв letters (каждую букву - не больше,
+// чем она встречается в letters), длиной не меньше minLength букв. Сначала длинные, при равной длине - по алфавиту
+func (ix *Index) Words(letters string, minLength int) []string {
+	ls := newLetterSet(letters, ix.key)
+	var res []string
+	for _, sig := range ix.signatures() {
+		if utf8.RuneCountInString(sig) < minLength {
+			continue
+		}
+		if _, ok := ls.counts(sig); ok {
+			res = append(res, ix.words[sig]...)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		li, lj := utf8.RuneCountInString(res[i]), utf8.RuneCountInString(res[j])
+		if li != lj {
+			return li > lj
+		}
+		return res[i] < res[j]
+	})
+	return res
+}
+
+// PhraseOptions - ограничения поиска фраз. Нулевое значение - ограничения по умолчанию
+type PhraseOptions struct {
+	MaxWords   int // Сколько слов может быть во фразе, по умолчанию 3
+	MinLength  int // Сколько букв должно быть в каждом слове, по умолчанию 1
+	MaxResults int // Сколько фраз найти, 0 - все
+}
+
+// candidate - ключ словаря, который помещается в наборе букв запроса
+type candidate struct {
+	sig    string
+	counts []int
+}
+
+// Phrases возвращает фразы из слов словаря, в которых вместе ровно те же буквы, что и в letters
+// (пробелы в letters не учитываются): "листок" -> "лис ток", "кот лис", ... и само "листок".
+// Слова во фразе идут в порядке их ключей, перестановки одной фразы не повторяются
+func (ix *Index) Phrases(letters string, opts PhraseOptions) [][]string {
+	if opts.MaxWords <= 0 {
+		opts.MaxWords = 3
+	}
+	ls := newLetterSet(letters, ix.key)
+	var candidates []candidate
+	for _, sig := range ix.signatures() {
+		if utf8.RuneCountInString(sig) < opts.MinLength {
+			continue
+		}
+		if counts, ok := ls.counts(sig); ok {
+			candidates = append(candidates, candidate{sig: sig, counts: counts})
+		}
+	}
+
+	var res [][]string
+	full := func() bool { return opts.MaxResults > 0 && len(res) >= opts.MaxResults }
+	remaining := append([]int(nil), ls.total...)
+	var chosen []int // Номера выбранных ключей, по неубыванию: так каждый набор ключей перебирается один раз
+	var search func(from, left int)
+	search = func(from, left int) {
+		if left == 0 { // Все буквы израсходованы - превращаем набор ключей во фразы
+			res = ix.expand(candidates, chosen, res, opts.MaxResults)
+			return
+		}
+		if len(chosen) == opts.MaxWords {
+			return
+		}
+		for i := from; i < len(candidates) && !full(); i++ {
+			c := candidates[i]
+			if !fits(c.counts, remaining) {
+				continue
+			}
+			addCounts(remaining, c.counts, -1)
+			chosen = append(chosen, i)
+			search(i, left-utf8.RuneCountInString(c.sig))
+			chosen = chosen[:len(chosen)-1]
+			addCounts(remaining, c.counts, 1)
+		}
+	}
+	total := 0
+	for _, n := range ls.total {
+		total += n
+	}
+	if total > 0 {
+		search(0, total)
+	}
+	return res
+}
+
+// expand добавляет к res все фразы из слов выбранных ключей. Если ключ выбран несколько раз,
+// его слова берутся по неубыванию номера, чтобы "кот кот"/"кот ток"/"ток кот" не дали одинаковых фраз
+func (ix *Index) expand(candidates []candidate, chosen []int, res [][]string, maxResults int) [][]string {
+	phrase := make([]string, len(chosen))
+	var fill func(pos, minWord int)
+	fill = func(pos, minWord int) {
+		if maxResults > 0 && len(res) >= maxResults {
+			return
+		}
+		if pos == len(chosen) {
+			res = append(res, append([]string(nil), phrase...))
+			return
+		}
+		words := ix.words[candidates[chosen[pos]].sig]
+		for w := minWord; w < len(words); w++ {
+			phrase[pos] = words[w]
+			next := 0
+			if pos+1 < len(chosen) && chosen[pos+1] == chosen[pos] {
+				next = w
+			}
+			fill(pos+1, next)
+		}
+	}
+	fill(0, 0)
+	return res
+}
+
+// fits сообщает, хватает ли remaining букв на counts
+func fits(counts, remaining []int) bool {
+	for i, n := range counts {
+		if n > remaining[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addCounts прибавляет к remaining counts, умноженные на sign: -1 - буквы израсходованы, 1 - возвращены
+func addCounts(remaining, counts []int, sign int) {
+	for i, n := range counts {
+		remaining[i] += sign * n
+	}
+}
@@ -0,0 +1,115 @@
+package anagram
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDictionary = "листок\nСлиток\nстолик\nлис\nток\nкот\nкто\nсок\nлиса\nил\nлик\nлисток\n"
+
+func buildIndex(t *testing.T, dict string) *Index {
+	ix, err := BuildIndex(strings.NewReader(dict), KeyOptions{})
+	require.NoError(t, err)
+	return ix
+}
+
+func TestIndexLookup(t *testing.T) {
+	ix := buildIndex(t, testDictionary)
+	assert.Equal(t, 11, ix.Len(), "повторы и регистр не добавляют слов")
+	testCases := []struct {
+		word string
+		want []string
+	}{
+		{"столик", []string{"листок", "слиток", "столик"}},
+		{"ТОЛИКС", []string{"листок", "слиток", "столик"}},
+		{"окт", []string{"кот", "кто", "ток"}},
+		{"мышь", nil},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, ix.Lookup(tc.word), tc.word)
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	ix := buildIndex(t, "кот\nток\nил\n")
+	var saved bytes.Buffer
+	require.NoError(t, ix.Save(&saved))
+	assert.Equal(t, "anagram-index 1 norm=none\nил\tил\nкот\tкот\tток\n", saved.String())
+
+	loaded, err := LoadIndex(&saved)
+	require.NoError(t, err)
+	assert.Equal(t, ix, loaded)
+
+	for _, bad := range []string{
+		"",
+		"anagram-index 2\n",
+		"anagram-index 1\nкот\n",
+		"anagram-index 1\nкот\tкит\n",
+	} {
+		_, err := LoadIndex(strings.NewReader(bad))
+		assert.True(t, errors.Is(err, errIndexFormat), "%q: %v", bad, err)
+	}
+}
+
+func TestIndexWords(t *testing.T) {
+	ix := buildIndex(t, testDictionary)
+	assert.Equal(t, []string{"листок", "слиток", "столик", "кот", "кто", "лик", "лис", "сок", "ток", "ил"}, ix.Words("листок", 0))
+	assert.Equal(t, []string{"кот", "кто", "сок", "ток"}, ix.Words("коток с", 3), "пробелы не буквы")
+	assert.Empty(t, ix.Words("ъ", 0))
+}
+
+func TestIndexPhrases(t *testing.T) {
+	ix := buildIndex(t, testDictionary)
+	join := func(phrases [][]string) []string {
+		var res []string
+		for _, p := range phrases {
+			res = append(res, strings.Join(p, " "))
+		}
+		return res
+	}
+	testCases := []struct {
+		name    string
+		letters string
+		opts    PhraseOptions
+		want    []string
+	}{
+		{
+			name:    "одно и два слова",
+			letters: "листок",
+			want:    []string{"листок", "слиток", "столик", "лис кот", "лис кто", "лис ток"},
+		},
+		{
+			name:    "не больше одного слова",
+			letters: "кот лис",
+			opts:    PhraseOptions{MaxWords: 1},
+			want:    []string{"листок", "слиток", "столик"},
+		},
+		{
+			name:    "ограничение количества",
+			letters: "листок",
+			opts:    PhraseOptions{MaxResults: 4},
+			want:    []string{"листок", "слиток", "столик", "лис кот"},
+		},
+		{
+			name:    "ключ дважды, без перестановок одной фразы",
+			letters: "коткот",
+			opts:    PhraseOptions{MaxWords: 2, MinLength: 3},
+			want:    []string{"кот кот", "кот кто", "кот ток", "кто кто", "кто ток", "ток ток"},
+		},
+		{
+			name:    "без букв",
+			letters: " ",
+			want:    nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, join(ix.Phrases(tc.letters, tc.opts)))
+		})
+	}
+}
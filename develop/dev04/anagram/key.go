@@ -0,0 +1,172 @@
+package anagram
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	errNormalization = errors.New("неизвестная нормализация")
+	errMinSize       = errors.New("в множестве должно быть хотя бы одно слово")
+	errKeyOption     = errors.New("неизвестная настройка ключа")
+)
+
+// Normalization - какую нормализацию Unicode применить к слову перед построением ключа
+type Normalization int
+
+const (
+	NormNone Normalization = iota // Слово как есть: "й" одним символом и "и" с бреве - разные буквы
+	NormNFC                       // Составные символы собираются: "й" всегда одна буква
+	NormNFKD                      // Совместимая декомпозиция: лигатуры распадаются ("ﬁ" -> "fi"), диакритика - отдельными знаками
+)
+
+var normalizationNames = []string{NormNone: "none", NormNFC: "nfc", NormNFKD: "nfkd"}
+
+func (n Normalization) String() string {
+	if n < NormNone || n > NormNFKD {
+		return fmt.Sprintf("Normalization(%d)", int(n))
+	}
+	return normalizationNames[n]
+}
+
+// ParseNormalization разбирает название нормализации: "none", "nfc" или "nfkd" (регистр не важен)
+func ParseNormalization(name string) (Normalization, error) {
+	for n, known := range normalizationNames {
+		if strings.EqualFold(name, known) {
+			return Normalization(n), nil
+		}
+	}
+	if name == "" {
+		return NormNone, nil
+	}
+	return NormNone, fmt.Errorf("%w: %q", errNormalization, name)
+}
+
+// KeyOptions - какие слова считать составленными из одних и тех же букв.
+// Нулевое значение - слова сравниваются как есть, различается только регистр (он не важен всегда)
+type KeyOptions struct {
+	Normalization Normalization
+	FoldYo        bool // Считать "ё" буквой "е": "ёлка" и "елка" - одно слово
+	IgnorePunct   bool // Не учитывать знаки препинания и пробелы: "кто-то" - анаграмма "оттотк"
+}
+
+// normalize приводит слово к виду, по которому строится ключ и сравниваются слова множества
+func (o KeyOptions) normalize(word string) string {
+	switch o.Normalization {
+	case NormNFC:
+		word = norm.NFC.String(word)
+	case NormNFKD:
+		word = norm.NFKD.String(word)
+	}
+	if o.FoldYo {
+		// После NFKD (или в несобранном слове) "ё" - это "е" и отдельный знак умлаута: его и убираем
+		word = strings.NewReplacer("ё", "е", "Ё", "Е", "е\u0308", "е", "Е\u0308", "Е").Replace(word)
+	}
+	if o.IgnorePunct {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) || unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, word)
+	}
+	return strings.ToLower(word)
+}
+
+// String записывает настройки словами через пробел: "norm=nfc yo nopunct". Обратное преобразование - parseKeyOptions
+func (o KeyOptions) String() string {
+	parts := []string{"norm=" + o.Normalization.String()}
+	if o.FoldYo {
+		parts = append(parts, "yo")
+	}
+	if o.IgnorePunct {
+		parts = append(parts, "nopunct")
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseKeyOptions разбирает настройки, записанные KeyOptions.String
+func parseKeyOptions(s string) (KeyOptions, error) {
+	var o KeyOptions
+	for _, part := range strings.Fields(s) {
+		switch {
+		case strings.HasPrefix(part, "norm="):
+			n, err := ParseNormalization(strings.TrimPrefix(part, "norm="))
+			if err != nil {
+				return KeyOptions{}, err
+			}
+			o.Normalization = n
+		case part == "yo":
+			o.FoldYo = true
+		case part == "nopunct":
+			o.IgnorePunct = true
+		default:
+			return KeyOptions{}, fmt.Errorf("%w: %q", errKeyOption, part)
+		}
+	}
+	return o, nil
+}
+
+// Key возвращает ключ множества анаграмм для слова с учётом настроек
+func (o KeyOptions) Key(word string) string {
+	return Signature(o.normalize(word))
+}
+
+// Option - функция, меняющая одну из настроек поиска (как cut.Option в dev06)
+type Option func(*Options) error
+
+// SetNormalizationOption задаёт нормализацию Unicode
+func SetNormalizationOption(n Normalization) Option {
+	return func(o *Options) error {
+		if n < NormNone || n > NormNFKD {
+			return errNormalization
+		}
+		o.Key.Normalization = n
+		return nil
+	}
+}
+
+// SetFoldYoOption включает или выключает отождествление "ё" и "е"
+func SetFoldYoOption(fold bool) Option {
+	return func(o *Options) error {
+		o.Key.FoldYo = fold
+		return nil
+	}
+}
+
+// SetIgnorePunctOption включает или выключает пропуск знаков препинания и пробелов
+func SetIgnorePunctOption(ignore bool) Option {
+	return func(o *Options) error {
+		o.Key.IgnorePunct = ignore
+		return nil
+	}
+}
+
+// SetMinSizeOption задаёт, сколько разных слов должно быть в множестве
+func SetMinSizeOption(size int) Option {
+	return func(o *Options) error {
+		if size < 1 {
+			return errMinSize
+		}
+		o.MinSize = size
+		return nil
+	}
+}
+
+// NewOptions возвращает настройки по умолчанию, изменённые функциями opts
+func NewOptions(opts ...Option) (Options, error) {
+	o := Options{}.withDefaults()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&o); err != nil {
+			return Options{}, err
+		}
+	}
+	return o, nil
+}
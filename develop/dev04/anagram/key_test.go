@@ -0,0 +1,116 @@
+package anagram
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	const (
+		yoComposed   = "ёлка"
+		yoDecomposed = "ёлка" // "е" и отдельный знак умлаута
+		shortI       = "чай"
+		shortIDecomp = "чай" // "и" и отдельный знак бреве
+	)
+	testCases := []struct {
+		name string
+		opts KeyOptions
+		a, b string
+		same bool
+	}{
+		{name: "регистр не важен всегда", a: "Пятка", b: "ТЯПКА", same: true},
+		{name: "ё и е различаются", a: "ёлка", b: "елка"},
+		{name: "ё как е", opts: KeyOptions{FoldYo: true}, a: "ёлка", b: "КЕЛА", same: true},
+		{name: "несобранная ё как е", opts: KeyOptions{FoldYo: true}, a: yoDecomposed, b: "елка", same: true},
+		{name: "без нормализации способ записи важен", a: shortI, b: shortIDecomp},
+		{name: "NFC", opts: KeyOptions{Normalization: NormNFC}, a: shortI, b: shortIDecomp, same: true},
+		{name: "NFKD", opts: KeyOptions{Normalization: NormNFKD}, a: shortI, b: shortIDecomp, same: true},
+		{name: "NFKD раскладывает лигатуры", opts: KeyOptions{Normalization: NormNFKD}, a: "ﬁle", b: "lief", same: true},
+		{name: "NFKD и ё", opts: KeyOptions{Normalization: NormNFKD, FoldYo: true}, a: yoComposed, b: "елка", same: true},
+		{name: "дефис и пробел - буквы", a: "кто-то", b: "откто"},
+		{name: "без знаков препинания", opts: KeyOptions{IgnorePunct: true}, a: "кто-то", b: "откто", same: true},
+		{name: "без пробелов", opts: KeyOptions{IgnorePunct: true}, a: "кот лис", b: "листок!", same: true},
+	}
+	for _, tc := range testCases {
+		same := tc.opts.Key(tc.a) == tc.opts.Key(tc.b)
+		assert.Equal(t, tc.same, same, "%s: %q и %q", tc.name, tc.a, tc.b)
+	}
+}
+
+func TestParseNormalization(t *testing.T) {
+	for name, want := range map[string]Normalization{"": NormNone, "none": NormNone, "NFC": NormNFC, "nfkd": NormNFKD} {
+		got, err := ParseNormalization(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	_, err := ParseNormalization("nfd")
+	assert.True(t, errors.Is(err, errNormalization))
+}
+
+func TestFindWithKeyOptions(t *testing.T) {
+	dict := []string{"Ёлка", "елка", "кела", "кто-то", "откто", "ток", "кот", "ёж", "еж"}
+	testCases := []struct {
+		name string
+		opts []Option
+		want map[string][]string
+	}{
+		{
+			name: "по умолчанию", // "Ёлка" не анаграмма "кела"
+			want: map[string][]string{"елка": {"елка", "кела"}, "ток": {"кот", "ток"}},
+		},
+		{
+			name: "ё как е: повтор слова не в счёт, остаётся первое написание",
+			opts: []Option{SetFoldYoOption(true)},
+			want: map[string][]string{"Ёлка": {"кела", "ёлка"}, "ток": {"кот", "ток"}},
+		},
+		{
+			name: "ё как е, множества из трёх слов",
+			opts: []Option{SetFoldYoOption(true), SetMinSizeOption(3)},
+			want: map[string][]string{},
+		},
+		{
+			name: "без знаков препинания",
+			opts: []Option{SetIgnorePunctOption(true), SetNormalizationOption(NormNFC)},
+			want: map[string][]string{"елка": {"елка", "кела"}, "кто-то": {"кто-то", "откто"}, "ток": {"кот", "ток"}},
+		},
+		{
+			name: "одиночные слова",
+			opts: []Option{SetMinSizeOption(1), SetFoldYoOption(true)},
+			want: map[string][]string{"Ёлка": {"кела", "ёлка"}, "кто-то": {"кто-то"}, "откто": {"откто"},
+				"ток": {"кот", "ток"}, "ёж": {"ёж"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FindWords(dict, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := FindWords(dict, SetMinSizeOption(0))
+	assert.Equal(t, errMinSize, err)
+	_, err = FindWords(dict, SetNormalizationOption(Normalization(7)))
+	assert.Equal(t, errNormalization, err)
+}
+
+func TestIndexWithKeyOptions(t *testing.T) {
+	key := KeyOptions{Normalization: NormNFC, FoldYo: true, IgnorePunct: true}
+	ix := NewIndex(key)
+	for _, w := range []string{"ёлка", "Елка", "кела", "кто-то", "откто"} {
+		ix.Add(w)
+	}
+	assert.Equal(t, []string{"кела", "ёлка"}, ix.Lookup("лЕКА"))
+	assert.Equal(t, []string{"кто-то", "откто"}, ix.Lookup("то кто"))
+
+	var saved bytes.Buffer
+	require.NoError(t, ix.Save(&saved))
+	assert.Contains(t, saved.String(), "anagram-index 1 norm=nfc yo nopunct\n")
+	loaded, err := LoadIndex(&saved)
+	require.NoError(t, err)
+	assert.Equal(t, ix, loaded)
+}
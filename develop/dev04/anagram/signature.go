@@ -0,0 +1,44 @@
+package anagram
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// runeCount - сколько раз буква встречается в слове
+type runeCount struct {
+	r rune
+	n int
+}
+
+// Signature возвращает ключ множества анаграмм: буквы слова в нижнем регистре по возрастанию ("пятка" -> "акптя").
+// У анаграмм ключи совпадают, у остальных слов - нет.
+// Буквы не сортируются по одной, а подсчитываются: сортируется только набор разных букв, которых в слове немного
+func Signature(word string) string {
+	counts := make([]runeCount, 0, 16)
+	for _, r := range word {
+		r = unicode.ToLower(r)
+		found := false
+		for i := range counts { // Линейный поиск: разных букв в слове единицы, map здесь дороже
+			if counts[i].r == r {
+				counts[i].n++
+				found = true
+				break
+			}
+		}
+		if !found {
+			counts = append(counts, runeCount{r: r, n: 1})
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].r < counts[j].r })
+
+	var b strings.Builder
+	b.Grow(len(word))
+	for _, c := range counts {
+		for i := 0; i < c.n; i++ {
+			b.WriteRune(c.r)
+		}
+	}
+	return b.String()
+}
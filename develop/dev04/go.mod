@@ -2,7 +2,10 @@ module dev04
 
 go 1.17
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/text v0.3.8
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
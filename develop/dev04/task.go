@@ -20,76 +20,247 @@
 package main
 
 import (
+	"bufio"
+	"dev04/anagram"
+	"encoding/json"
+	"flag"
 	"fmt"
-	"sort"
+	"io"
+	"os"
 	"strings"
 )
 
-// SortCharsInWord сортирует символы в слове в алфавитном порядке
+// SortCharsInWord сортирует символы в слове в алфавитном порядке (это ключ множества анаграмм, см. anagram.Signature)
 func SortCharsInWord(s string) string {
-	s = strings.ToLower(s)             // Приводим все символы в слове к нижнему регистру
-	container := strings.Split(s, "")  // Разбиваем слово на отдельные буквы, ...
-	sort.Strings(container)            // ... сортируем буквы из слова, ...
-	return strings.Join(container, "") // ... а затем соединяем буквы обратно в слово.
+	return anagram.Signature(s)
 }
 
-// CreateSet формирует слайс только из уникальных значений (формирует множество)
-func CreateSet(temp []string) []string {
-	// На вход CreateSet передаётся слайс строк, в котором значения элементов могут совпадать
-	mapSet := make(map[string]bool)
-	var sliceSet []string
-	for _, v := range temp { // перебираем элементы слайса
-		v = strings.ToLower(v)
-		mapSet[v] = true // добавляем элемент булевого типа в мапу по ключу - элементу слайса (слову)
+// FindAnagrams ищет анаграммы в исходном массиве, возвращает мапу множеств анаграмм.
+// Ключ - первое встретившееся слово множества (как оно записано в массиве), значение - слова множества
+// в нижнем регистре, без повторов, по возрастанию. Какие слова считать анаграммами, задают opts:
+// anagram.SetNormalizationOption, anagram.SetFoldYoOption, anagram.SetIgnorePunctOption, anagram.SetMinSizeOption.
+// Ошибка - неверная опция или не удалось создать временный файл: так её не спутать со словарём без анаграмм
+func FindAnagrams(arr []string, opts ...anagram.Option) (map[string][]string, error) {
+	return anagram.FindWords(arr, opts...)
+}
+
+// Утилита находит множества анаграмм в словаре (файле или стандартном вводе, по слову на строку)
+// и печатает их текстом или в JSON. Словарь не загружается в память целиком (см. пакет anagram).
+// Кроме того, по словарю можно построить индекс, сохранить его в файл и отвечать по нему на запросы:
+// анаграммы слова, слова и фразы из набора букв
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// query - запрос к индексу: что искать и с какими ограничениями
+type query struct {
+	lookup  string // Анаграммы слова
+	letters string // Слова из набора букв
+	phrase  string // Фразы из набора букв
+	phrases anagram.PhraseOptions
+}
+
+func (q *query) empty() bool { return q.lookup == "" && q.letters == "" && q.phrase == "" }
+
+// run выполняет утилиту с аргументами args и возвращает код возврата.
+// Ввод-вывод передаётся параметрами, чтобы программу целиком можно было прогнать в тестах
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("anagrams", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Поиск анаграмм. Использование: ./[название_исполняемого_файла] [опции] [СЛОВАРЬ]")
+		fmt.Fprintln(stderr, "Без словаря (или со словарём \"-\") читается стандартный ввод, по слову на строку.")
+		fmt.Fprintln(stderr, "Без -save, -lookup, -letters и -phrase печатаются все множества анаграмм словаря.")
+		fmt.Fprintln(stderr, "Доступные опции:")
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "печатать множества в JSON: массив объектов {\"key\": ..., \"words\": [...]}")
+	minSize := flags.Int("min", 2, "сколько разных слов должно быть в множестве")
+	memory := flags.Int64("memory", 64, "сколько МиБ памяти занимать под слова; остальное - во временных файлах")
+	tempDir := flags.String("tmp", "", "каталог для временных файлов (по умолчанию $TMPDIR или /tmp)")
+	normalization := flags.String("norm", "none", "нормализация Unicode перед сравнением букв: none, nfc или nfkd (при -index - как при -save)")
+	foldYo := flags.Bool("yo", false, "считать \"ё\" буквой \"е\"")
+	ignorePunct := flags.Bool("nopunct", false, "не учитывать знаки препинания и пробелы в словах")
+	save := flags.String("save", "", "построить по словарю индекс и записать его в `ФАЙЛ`")
+	indexFile := flags.String("index", "", "отвечать на запросы по индексу из `ФАЙЛА` (см. -save), а не по словарю")
+	var q query
+	flags.StringVar(&q.lookup, "lookup", "", "напечатать все анаграммы `СЛОВА` из словаря")
+	flags.StringVar(&q.letters, "letters", "", "напечатать слова, которые можно составить из `БУКВ`")
+	flags.StringVar(&q.phrase, "phrase", "", "напечатать фразы, в которых ровно те же `БУКВЫ`")
+	flags.IntVar(&q.phrases.MaxWords, "words", 3, "сколько слов может быть во фразе")
+	flags.IntVar(&q.phrases.MaxResults, "limit", 0, "сколько фраз напечатать, 0 - все")
+	flags.IntVar(&q.phrases.MinLength, "minlen", 1, "сколько букв должно быть в словах (-letters, -phrase)")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	modes := 0
+	for _, set := range []bool{*save != "", q.lookup != "", q.letters != "", q.phrase != ""} {
+		if set {
+			modes++
+		}
 	}
-	for i := range mapSet {
-		sliceSet = append(sliceSet, i)
+	norm, err := anagram.ParseNormalization(*normalization)
+	if err != nil || flags.NArg() > 1 || *minSize < 1 || *memory < 1 || q.phrases.MaxWords < 1 || q.phrases.MaxResults < 0 ||
+		modes > 1 || (*indexFile != "" && (q.empty() || flags.NArg() > 0)) {
+		flags.Usage()
+		return 2
 	}
-	sort.Strings(sliceSet)
-	return sliceSet
+
+	key := anagram.KeyOptions{Normalization: norm, FoldYo: *foldYo, IgnorePunct: *ignorePunct} // При -index - из файла индекса
+	w := bufio.NewWriter(stdout)
+	if *indexFile != "" {
+		err = queryIndexFile(*indexFile, &q, w)
+	} else {
+		err = withInput(flags.Arg(0), stdin, func(input io.Reader) error {
+			switch {
+			case *save != "":
+				return saveIndex(input, *save, key)
+			case !q.empty():
+				ix, err := anagram.BuildIndex(input, key)
+				if err != nil {
+					return err
+				}
+				return queryIndex(ix, &q, w)
+			}
+			opts := anagram.Options{MinSize: *minSize, MemoryLimit: *memory << 20, TempDir: *tempDir, Key: key}
+			return printGroups(input, opts, *asJSON, w)
+		})
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "anagrams: %v\n", err)
+		return 1
+	}
+	return 0
 }
 
-// Функция printAnagrams печатает содержимое получившейся мапы в форматированном виде
-func printAnagrams(bucket map[string][]string) {
-	for k, v := range bucket {
-		fmt.Printf("%v : %v\n", k, v)
+// withInput открывает словарь name ("" и "-" - стандартный ввод) и передаёт его в f
+func withInput(name string, stdin io.Reader, f func(io.Reader) error) error {
+	if name == "" || name == "-" {
+		return f(stdin)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+	return f(file)
 }
 
-// FindAnagrams ищет анаграммы в исходном массиве, возвращает мапу множеств анаграмм
-func FindAnagrams(arr []string) map[string][]string {
-	tempMap := make(map[string][]string, 0)   // Промежуточная мапа для элементов, распределенных по множествам (значения по ключу могут повторяться (условие, что каждый эл-т в мн-ве - уникальный, может не выполняться))
-	elemPosition := make(map[string][]int, 0) // Промежуточная мапа для хранения позиций элементов каждого из множеств в исходном массиве
-	res := make(map[string][]string, 0)       // Результирующая мапа, где все анаграммы распределены по соответствующим множествам, нет дубляжей, а названия этих множеств (ключи) - первые встретившиеся слова, принадлежащие соответствующим множествам
-	tempSetnames := make([]string, 0)
-	// Если в исходном массиве будут попадаться слова, являющиеся анаграммами по отношению друг к другу (то есть в отсортированном по буквам виде эти слова будут представлять одинаковые значения),
-	// мы добавим их (и их индексы в исходном массиве) по одному и тому же ключу в мапу (пятак, пятка и тяпка будут добавлены в мапу (в слайс строк) по ключу "акптя")
-	for i, v := range arr { // Перебираем слова
-		sortedWord := SortCharsInWord(v) // Сортируем слово по буквам
-		fmt.Println(sortedWord)
-		elemPosition[sortedWord] = append(elemPosition[sortedWord], i) // Добавляем в мапу (в слайс интов) элемент (индекс данного слова в исходном массиве) по ключу - этому слову в отсортированном виде
-		tempMap[sortedWord] = append(tempMap[sortedWord], v)
-		// На этом этапе слова уже распределены по множествам, но:
-		// 1. В каждом из множеств могут встречаться повторения. (используем CreateSet, чтобы устранить это)
-		// 2. Ключи пока - наборы букв из этих анаграмм, а не первое встретившееся в словаре слово из множества
+// printGroups печатает множества анаграмм словаря по мере их нахождения
+func printGroups(input io.Reader, opts anagram.Options, asJSON bool, w *bufio.Writer) error {
+	var p groupPrinter = &textPrinter{w: w}
+	if asJSON {
+		p = &jsonPrinter{w: w}
 	}
+	if err := anagram.Find(input, opts, p.group); err != nil {
+		return err
+	}
+	return p.finish()
+}
 
-	for k, _ := range tempMap { // Перебираем ключи временной мапы ("акптя" и т. д.)...
-		fmt.Println("k:", k)
-		tempSetnames = append(tempSetnames, k) // ...добавляем их в слайс строк
+// saveIndex строит индекс по словарю и записывает его в файл path
+func saveIndex(input io.Reader, path string, key anagram.KeyOptions) error {
+	ix, err := anagram.BuildIndex(input, key)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	err = ix.Save(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
 
-	for _, setName := range tempSetnames {
-		firstOne := elemPosition[setName][0]             // Находим для 1-го элемента из одного из сформированных множеств его позицию в исходном массиве строк ("первый встретившийся" из условия)
-		res[arr[firstOne]] = CreateSet(tempMap[setName]) // CreateSet вернёт слайс уникальных значений, его мы запишем в мапу по ключу, являющемуся первым встретившимся словом из данного множества
-		// Причём ключами в получившейся мапе будут значения из исходного массива (словаря) с сохранённым регистром
+// queryIndexFile загружает индекс из файла path и отвечает по нему на запрос
+func queryIndexFile(path string, q *query, w *bufio.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	ix, err := anagram.LoadIndex(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return queryIndex(ix, q, w)
+}
 
-	return res
+// queryIndex печатает ответ на запрос: по слову или фразе на строку
+func queryIndex(ix *anagram.Index, q *query, w *bufio.Writer) error {
+	var lines []string
+	switch {
+	case q.lookup != "":
+		lines = ix.Lookup(q.lookup)
+	case q.letters != "":
+		lines = ix.Words(q.letters, q.phrases.MinLength)
+	case q.phrase != "":
+		for _, phrase := range ix.Phrases(q.phrase, q.phrases) {
+			lines = append(lines, strings.Join(phrase, " "))
+		}
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func main() {
-	words := []string{"ПЯТка", "пятак", "тяпка", "ЛИСТОК", "слиток", "столик", "листок"}
-	anagrams := FindAnagrams(words)
-	printAnagrams(anagrams)
+// groupPrinter печатает множества по мере того, как они находятся
+type groupPrinter interface {
+	group(g anagram.Group) error
+	finish() error // Дописывает то, что нужно после последнего множества
+}
+
+// textPrinter печатает множество строкой "ключ: слово слово слово"
+type textPrinter struct {
+	w *bufio.Writer
+}
+
+func (p *textPrinter) group(g anagram.Group) error {
+	_, err := fmt.Fprintf(p.w, "%s: %s\n", g.Key, strings.Join(g.Words, " "))
+	return err
+}
+
+func (p *textPrinter) finish() error { return nil }
+
+// jsonPrinter печатает множества JSON-массивом, по объекту на строку. Массив пишется потоком,
+// а не собирается целиком: множеств в большом словаре может быть много
+type jsonPrinter struct {
+	w     *bufio.Writer
+	count int // Сколько множеств уже напечатано
+}
+
+func (p *jsonPrinter) group(g anagram.Group) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	prefix := ",\n"
+	if p.count == 0 {
+		prefix = "[\n"
+	}
+	p.count++
+	p.w.WriteString(prefix)
+	_, err = p.w.Write(data)
+	return err
+}
+
+func (p *jsonPrinter) finish() error {
+	closing := "\n]\n"
+	if p.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := p.w.WriteString(closing)
+	return err
 }
@@ -1,8 +1,13 @@
 package main
 
 import (
-	"github.com/stretchr/testify/assert"
+	"bytes"
+	"dev04/anagram"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSortCharsInWords(t *testing.T) {
@@ -48,7 +53,102 @@ func TestFindAnagrams(t *testing.T) {
 	}
 
 	for _, v := range testCases {
-		result := FindAnagrams(v.in)
+		result, err := FindAnagrams(v.in)
+		assert.NoError(t, err)
 		assert.Equal(t, result, v.out)
 	}
-}
\ No newline at end of file
+}
+func TestRun(t *testing.T) {
+	dict := "ПЯТка\nпятак\nтяпка\nЛИСТОК\nслиток\nстолик\nлисток\nкот\n"
+	testCases := []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{nil, "ПЯТка: пятак пятка тяпка\nЛИСТОК: листок слиток столик\n", 0},
+		{[]string{"-min", "3", "-"}, "ПЯТка: пятак пятка тяпка\nЛИСТОК: листок слиток столик\n", 0},
+		{[]string{"-min", "4"}, "", 0},
+		{[]string{"-json", "-memory", "1"}, "[\n" +
+			`{"key":"ПЯТка","words":["пятак","пятка","тяпка"]},` + "\n" +
+			`{"key":"ЛИСТОК","words":["листок","слиток","столик"]}` + "\n]\n", 0},
+		{[]string{"-json", "-min", "5"}, "[]\n", 0},
+		{[]string{"нет-такого-словаря"}, "", 1},
+		{[]string{"-min", "0"}, "", 2},
+		{[]string{"-unknown"}, "", 2},
+	}
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		code := run(tc.args, strings.NewReader(dict), &stdout, &stderr)
+		assert.Equal(t, tc.code, code, "%v: %s", tc.args, stderr.String())
+		assert.Equal(t, tc.out, stdout.String(), "%v", tc.args)
+	}
+}
+
+func TestRunIndex(t *testing.T) {
+	dir := t.TempDir()
+	index := filepath.Join(dir, "index.txt")
+	dict := "листок\nслиток\nстолик\nлис\nток\nкот\n"
+	testCases := []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{[]string{"-save", index}, "", 0},
+		{[]string{"-index", index, "-lookup", "Столик"}, "листок\nслиток\nстолик\n", 0},
+		{[]string{"-index", index, "-letters", "токсил", "-minlen", "4"}, "листок\nслиток\nстолик\n", 0},
+		{[]string{"-index", index, "-phrase", "кот лис", "-words", "2", "-limit", "4"}, "листок\nслиток\nстолик\nлис кот\n", 0},
+		{[]string{"-lookup", "кто"}, "кот\nток\n", 0}, // Без -index индекс строится по словарю
+		{[]string{"-index", index}, "", 2},
+		{[]string{"-lookup", "кот", "-letters", "кот"}, "", 2},
+		{[]string{"-index", filepath.Join(dir, "нет"), "-lookup", "кот"}, "", 1},
+	}
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		code := run(tc.args, strings.NewReader(dict), &stdout, &stderr)
+		assert.Equal(t, tc.code, code, "%v: %s", tc.args, stderr.String())
+		assert.Equal(t, tc.out, stdout.String(), "%v", tc.args)
+	}
+}
+
+func TestFindAnagramsWithOptions(t *testing.T) {
+	words := []string{"Ёлка", "ель", "кела", "лье", "ёж", "жё", "кто-то", "откто"}
+	testCases := []struct {
+		opts []anagram.Option
+		out  map[string][]string
+	}{
+		{nil, map[string][]string{"ель": {"ель", "лье"}, "ёж": {"жё", "ёж"}}},
+		{[]anagram.Option{anagram.SetFoldYoOption(true)},
+			map[string][]string{"Ёлка": {"кела", "ёлка"}, "ель": {"ель", "лье"}, "ёж": {"жё", "ёж"}}},
+		{[]anagram.Option{anagram.SetIgnorePunctOption(true), anagram.SetMinSizeOption(2)},
+			map[string][]string{"ель": {"ель", "лье"}, "ёж": {"жё", "ёж"}, "кто-то": {"кто-то", "откто"}}},
+	}
+	for _, tc := range testCases {
+		res, err := FindAnagrams(words, tc.opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.out, res)
+	}
+	res, err := FindAnagrams(words, anagram.SetMinSizeOption(0))
+	assert.Error(t, err, "неверная опция")
+	assert.Nil(t, res)
+}
+
+func TestRunKeyOptions(t *testing.T) {
+	dict := "Ёлка\nкела\nкто-то\nоткто\n"
+	testCases := []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{nil, "", 0},
+		{[]string{"-yo"}, "Ёлка: кела ёлка\n", 0},
+		{[]string{"-yo", "-nopunct", "-norm", "NFKD"}, "Ёлка: кела ёлка\nкто-то: кто-то откто\n", 0},
+		{[]string{"-yo", "-lookup", "ЕЛКА"}, "кела\nёлка\n", 0},
+		{[]string{"-norm", "nfd"}, "", 2},
+	}
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		code := run(tc.args, strings.NewReader(dict), &stdout, &stderr)
+		assert.Equal(t, tc.code, code, "%v: %s", tc.args, stderr.String())
+		assert.Equal(t, tc.out, stdout.String(), "%v", tc.args)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"dev05/grep"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	errNoPattern       = errors.New("не указан искомый шаблон")
+	errMissingArgument = errors.New("опции требуется аргумент")
+	errUnknownOption   = errors.New("неизвестная опция")
+	errContextLength   = errors.New("неверная длина контекста")
+	errBinaryFiles     = errors.New("неизвестный тип двоичных файлов")
+	errJobs            = errors.New("число потоков должно быть положительным")
+	errMaxCount        = errors.New("неверное максимальное число совпадений")
+	errColor           = errors.New("неизвестный режим подсветки")
+	errMatchers        = errors.New("conflicting matchers specified") // Текст как у GNU grep
+)
+
+// options - результат разбора командной строки
+type options struct {
+	cfg        grep.Config
+	walk       grep.WalkConfig
+	files      []string // Файлы и каталоги для поиска, пусто - стандартный ввод (или текущий каталог при -r)
+	context    int      // -C: контекст по умолчанию с обеих сторон
+	afterSet   bool     // Был ли явно задан -A
+	beforeSet  bool     // Был ли явно задан -B
+	filename   bool     // -H: печатать имя файла всегда
+	noFilename bool     // -h: не печатать имя файла никогда
+	jobs       int      // -j: сколько файлов просматривать одновременно
+	maxCount   int      // -m: после скольких выбранных строк остановиться, отрицательное - без ограничения
+	color      string   // --color: auto, always или never
+	help       bool
+
+	syntaxSet bool            // Был ли явно задан диалект (-G, -E, -F, -P)
+	conflict  bool            // Заданы разные диалекты
+	sources   []patternSource // Шаблоны из -e и -f в порядке указания; пусто - шаблон первый позиционный аргумент
+}
+
+// patternSource - один -e ШАБЛОН или -f ФАЙЛ
+type patternSource struct {
+	value  string
+	isFile bool
+}
+
+// config возвращает итоговую конфигурацию поиска
+func (o *options) config() grep.Config {
+	cfg := o.cfg
+	// Как и в GNU grep, -C лишь задаёт значение по умолчанию, а явные -A/-B имеют приоритет вне зависимости от порядка флагов
+	if !o.afterSet {
+		cfg.After = o.context
+	}
+	if !o.beforeSet {
+		cfg.Before = o.context
+	}
+	// Имя файла печатается, если источников может быть несколько (в архиве их тоже несколько)
+	cfg.WithFilename = o.filename || (!o.noFilename && (len(o.files) > 1 || o.walk.Recursive || o.cfg.Archives))
+	if o.maxCount > 0 {
+		cfg.MaxCount = o.maxCount
+	}
+	return cfg
+}
+
+// boolFlag - флаг без аргумента: короткое имя (0 - только длинное), длинное имя и действие при его указании
+type boolFlag struct {
+	short byte
+	long  string
+	set   func()
+}
+
+func (o *options) boolFlags() []boolFlag {
+	return []boolFlag{
+		{'c', "count", func() { o.cfg.Count = true }},
+		{'i', "ignore-case", func() { o.cfg.IgnoreCase = true }},
+		{'v', "invert-match", func() { o.cfg.Invert = true }},
+		{'G', "basic-regexp", func() { o.setSyntax(grep.SyntaxBasic) }},
+		{'E', "extended-regexp", func() { o.setSyntax(grep.SyntaxExtended) }},
+		{'F', "fixed-strings", func() { o.setSyntax(grep.SyntaxFixed) }},
+		{'P', "perl-regexp", func() { o.setSyntax(grep.SyntaxPerl) }},
+		{'w', "word-regexp", func() { o.cfg.WordRegexp = true }},
+		{'x', "line-regexp", func() { o.cfg.LineRegexp = true }},
+		{'n', "line-number", func() { o.cfg.LineNum = true }},
+		{'r', "recursive", func() { o.walk.Recursive = true }},
+		{'R', "dereference-recursive", func() { o.walk.Recursive, o.walk.FollowSymlinks = true, true }},
+		{'l', "files-with-matches", func() { o.cfg.ListMatching, o.cfg.ListNonMatching = true, false }}, // Из -l и -L действует последний
+		{'L', "files-without-match", func() { o.cfg.ListMatching, o.cfg.ListNonMatching = false, true }},
+		{'H', "with-filename", func() { o.filename, o.noFilename = true, false }},
+		{'h', "no-filename", func() { o.filename, o.noFilename = false, true }},
+		{'a', "text", func() { o.cfg.Binary = grep.BinaryText }},
+		{'o', "only-matching", func() { o.cfg.OnlyMatching = true }},
+		{'b', "byte-offset", func() { o.cfg.ByteOffset = true }},
+		{0, "json", func() { o.cfg.JSON = true }},
+		{'z', "decompress", func() { o.cfg.Decompress = true }}, // В GNU grep -z - это --null-data, здесь же, как в ripgrep, - распаковка
+		{0, "archives", func() { o.cfg.Archives = true }},
+		{'I', "", func() { o.cfg.Binary = grep.BinaryWithoutMatch }},
+		{0, "no-ignore", func() { o.walk.NoIgnore = true }},
+		{0, "help", func() { o.help = true }},
+	}
+}
+
+// valueFlags - длинные опции со значением
+func (o *options) valueFlags() map[string]func(string) error {
+	return map[string]func(string) error{
+		"after-context":  func(v string) error { return o.setContext('A', v) },
+		"before-context": func(v string) error { return o.setContext('B', v) },
+		"context":        func(v string) error { return o.setContext('C', v) },
+		"include":        func(v string) error { o.walk.Include = append(o.walk.Include, v); return nil },
+		"exclude":        func(v string) error { o.walk.Exclude = append(o.walk.Exclude, v); return nil },
+		"exclude-dir":    func(v string) error { o.walk.ExcludeDir = append(o.walk.ExcludeDir, v); return nil },
+		"binary-files":   o.setBinaryFiles,
+		"jobs":           o.setJobs,
+		"max-count":      o.setMaxCount,
+		"regexp":         o.addPattern,
+		"file":           o.addPatternFile,
+	}
+}
+
+// shortValueFlags - короткие опции со значением
+func (o *options) shortValueFlags() map[byte]func(string) error {
+	return map[byte]func(string) error{
+		'A': func(v string) error { return o.setContext('A', v) },
+		'B': func(v string) error { return o.setContext('B', v) },
+		'C': func(v string) error { return o.setContext('C', v) },
+		'j': o.setJobs,
+		'm': o.setMaxCount,
+		'e': o.addPattern,
+		'f': o.addPatternFile,
+	}
+}
+
+// setSyntax выбирает диалект шаблонов. Повторять один и тот же флаг можно, а разные - ошибка, как в GNU grep
+func (o *options) setSyntax(syntax grep.Syntax) {
+	if o.syntaxSet && o.cfg.Syntax != syntax {
+		o.conflict = true
+	}
+	o.cfg.Syntax, o.syntaxSet = syntax, true
+}
+
+// addPattern разбирает -e ШАБЛОН
+func (o *options) addPattern(value string) error {
+	o.sources = append(o.sources, patternSource{value: value})
+	return nil
+}
+
+// addPatternFile разбирает -f ФАЙЛ; сам файл читается позже, в loadPatterns
+func (o *options) addPatternFile(value string) error {
+	o.sources = append(o.sources, patternSource{value: value, isFile: true})
+	return nil
+}
+
+// loadPatterns собирает шаблоны из -e и -f. Шаблон с переводами строк - это несколько шаблонов,
+// в файле шаблонов каждая строка - отдельный шаблон, "-" - стандартный ввод
+func (o *options) loadPatterns(stdin io.Reader) error {
+	for _, src := range o.sources {
+		if !src.isFile {
+			o.cfg.Patterns = append(o.cfg.Patterns, strings.Split(src.value, "\n")...)
+			continue
+		}
+		r := stdin
+		if src.value != grep.Stdin {
+			f, err := os.Open(src.value)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<30) // Шаблон бывает длинной строкой, стандартных 64К может не хватить
+		for sc.Scan() {
+			o.cfg.Patterns = append(o.cfg.Patterns, sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setContext присваивает значение одному из флагов -A, -B, -C
+func (o *options) setContext(name byte, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("%w: %q", errContextLength, value)
+	}
+	switch name {
+	case 'A':
+		o.cfg.After, o.afterSet = n, true
+	case 'B':
+		o.cfg.Before, o.beforeSet = n, true
+	case 'C':
+		o.context = n
+	}
+	return nil
+}
+
+// setJobs разбирает -j N
+func (o *options) setJobs(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%w: %q", errJobs, value)
+	}
+	o.jobs = n
+	return nil
+}
+
+// setMaxCount разбирает -m NUM
+func (o *options) setMaxCount(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%w: %q", errMaxCount, value)
+	}
+	o.maxCount = n
+	return nil
+}
+
+// setColor разбирает --color[=auto|always|never]
+func (o *options) setColor(value string) error {
+	switch value {
+	case "auto", "always", "never":
+		o.color = value
+		return nil
+	case "tty", "if-tty": // Синонимы, которые понимает и GNU grep
+		o.color = "auto"
+		return nil
+	case "yes", "force":
+		o.color = "always"
+		return nil
+	case "no", "none":
+		o.color = "never"
+		return nil
+	}
+	return fmt.Errorf("%w: %q", errColor, value)
+}
+
+// setBinaryFiles разбирает --binary-files=binary|text|without-match
+func (o *options) setBinaryFiles(value string) error {
+	modes := map[string]grep.BinaryMode{
+		"binary":        grep.BinaryMatches,
+		"text":          grep.BinaryText,
+		"without-match": grep.BinaryWithoutMatch,
+	}
+	mode, ok := modes[value]
+	if !ok {
+		return fmt.Errorf("%w: %q", errBinaryFiles, value)
+	}
+	o.cfg.Binary = mode
+	return nil
+}
+
+// parseArgs разбирает аргументы так же, как GNU grep: grep [ОПЦИИ] ШАБЛОН [ФАЙЛ...].
+// Короткие флаги можно объединять (-inv), значение пишется слитно или отдельно (-A2, -A 2),
+// опции могут идти и после шаблона, "--" завершает список опций
+func parseArgs(args []string) (*options, error) {
+	o := &options{jobs: runtime.NumCPU(), maxCount: -1, color: "never"}
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--": // Всё, что дальше, - позиционные аргументы, даже если начинается с "-"
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			if err := o.parseLong(arg[2:], args, &i); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1: // Одиночный "-" - это стандартный ввод
+			if err := o.parseShort(arg[1:], args, &i); err != nil {
+				return nil, err
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if o.help {
+		return o, nil
+	}
+	if o.conflict {
+		return nil, errMatchers
+	}
+	if len(o.sources) > 0 { // Шаблоны заданы через -e/-f - все позиционные аргументы считаются файлами
+		o.files = positional
+		return o, nil
+	}
+	if len(positional) == 0 {
+		return nil, errNoPattern
+	}
+	o.sources, o.files = []patternSource{{value: positional[0]}}, positional[1:]
+	return o, nil
+}
+
+// parseShort разбирает группу коротких флагов (то, что после "-")
+func (o *options) parseShort(group string, args []string, i *int) error {
+	for j := 0; j < len(group); j++ {
+		name := group[j]
+		if set, ok := o.shortValueFlags()[name]; ok {
+			value := group[j+1:] // Значение записано слитно: -A2
+			if value == "" {     // Иначе это следующий аргумент: -A 2
+				if *i+1 >= len(args) {
+					return fmt.Errorf("%w: -%c", errMissingArgument, name)
+				}
+				*i++
+				value = args[*i]
+			}
+			return set(value)
+		}
+		known := false
+		for _, f := range o.boolFlags() {
+			if f.short != 0 && f.short == name {
+				f.set()
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: -%c", errUnknownOption, name)
+		}
+	}
+	return nil
+}
+
+// parseLong разбирает длинную опцию (то, что после "--")
+func (o *options) parseLong(arg string, args []string, i *int) error {
+	name, value, hasValue := arg, "", false
+	if eq := strings.IndexByte(arg, '='); eq >= 0 { // Значение записано через "=": --context=2
+		name, value, hasValue = arg[:eq], arg[eq+1:], true
+	}
+	if name == "color" || name == "colour" { // Значение у --color необязательное и пишется только через "="
+		if !hasValue {
+			value = "auto"
+		}
+		return o.setColor(value)
+	}
+	if set, ok := o.valueFlags()[name]; ok {
+		if !hasValue {
+			if *i+1 >= len(args) {
+				return fmt.Errorf("%w: --%s", errMissingArgument, name)
+			}
+			*i++
+			value = args[*i]
+		}
+		return set(value)
+	}
+	for _, f := range o.boolFlags() {
+		if f.long != "" && f.long == name && !hasValue {
+			f.set()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: --%s", errUnknownOption, name)
+}
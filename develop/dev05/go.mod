@@ -1,3 +1,5 @@
 module dev05
 
 go 1.17
+
+require github.com/klauspost/compress v1.15.15
@@ -0,0 +1,127 @@
+package grep
+
+import "sort"
+
+// acNode - вершина бора Ахо-Корасик
+type acNode struct {
+	next   map[byte]int // Переходы по байту
+	fail   int          // Суффиксная ссылка: самый длинный собственный суффикс пути, который тоже есть в боре
+	length int          // Длина шаблона, заканчивающегося в этой вершине, 0 - шаблон здесь не заканчивается
+	output int          // Ближайшая по суффиксным ссылкам вершина, где заканчивается шаблон, -1 - такой нет
+}
+
+// ahoCorasick ищет сразу много строк за один проход по тексту: время поиска не зависит от числа шаблонов.
+// Используется для -F с несколькими шаблонами (-e, -f)
+type ahoCorasick struct {
+	nodes []acNode
+	empty bool // Среди шаблонов есть пустая строка - совпадает любая строка текста
+}
+
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{next: map[byte]int{}, output: -1}}}
+	for _, p := range patterns { // Строим бор
+		if p == "" {
+			ac.empty = true
+			continue
+		}
+		cur := 0
+		for i := 0; i < len(p); i++ {
+			nxt, ok := ac.nodes[cur].next[p[i]]
+			if !ok {
+				ac.nodes = append(ac.nodes, acNode{next: map[byte]int{}, output: -1})
+				nxt = len(ac.nodes) - 1
+				ac.nodes[cur].next[p[i]] = nxt
+			}
+			cur = nxt
+		}
+		ac.nodes[cur].length = len(p)
+	}
+
+	queue := make([]int, 0, len(ac.nodes)) // Суффиксные ссылки считаем обходом в ширину: ссылка ведёт на вершину меньшей глубины
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for c, child := range ac.nodes[cur].next {
+			f := ac.nodes[cur].fail
+			for f != 0 {
+				if _, ok := ac.nodes[f].next[c]; ok {
+					break
+				}
+				f = ac.nodes[f].fail
+			}
+			if nxt, ok := ac.nodes[f].next[c]; ok && nxt != child {
+				f = nxt
+			}
+			ac.nodes[child].fail = f
+			if ac.nodes[f].length > 0 {
+				ac.nodes[child].output = f
+			} else {
+				ac.nodes[child].output = ac.nodes[f].output
+			}
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+// step переходит из вершины cur по байту c
+func (ac *ahoCorasick) step(cur int, c byte) int {
+	for {
+		if nxt, ok := ac.nodes[cur].next[c]; ok {
+			return nxt
+		}
+		if cur == 0 {
+			return 0
+		}
+		cur = ac.nodes[cur].fail
+	}
+}
+
+func (ac *ahoCorasick) Match(line []byte) bool {
+	if ac.empty {
+		return true
+	}
+	cur := 0
+	for i := 0; i < len(line); i++ {
+		cur = ac.step(cur, line[i])
+		if ac.nodes[cur].length > 0 || ac.nodes[cur].output >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAll возвращает непересекающиеся совпадения по правилу "самое левое, из них самое длинное", как GNU grep -o
+func (ac *ahoCorasick) FindAll(line []byte) [][]int {
+	var all [][]int
+	cur := 0
+	for i := 0; i < len(line); i++ {
+		cur = ac.step(cur, line[i])
+		for n := cur; n > 0; n = ac.nodes[n].output { // Все шаблоны, заканчивающиеся в позиции i
+			if l := ac.nodes[n].length; l > 0 {
+				all = append(all, []int{i + 1 - l, i + 1})
+			}
+			if ac.nodes[n].output < 0 {
+				break
+			}
+		}
+	}
+	sort.Slice(all, func(a, b int) bool {
+		if all[a][0] != all[b][0] {
+			return all[a][0] < all[b][0]
+		}
+		return all[a][1] > all[b][1]
+	})
+	var spans [][]int
+	end := 0
+	for _, span := range all {
+		if span[0] >= end {
+			spans = append(spans, span)
+			end = span[1]
+		}
+	}
+	return spans
+}
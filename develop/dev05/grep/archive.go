@@ -0,0 +1,166 @@
+package grep
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Сжатый поток и архив узнаём по сигнатуре в начале данных, а не по расширению:
+// ротированный лог может называться app.log.1, а архив - без расширения вовсе
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicZip   = []byte("PK\x03\x04")
+	magicTar   = []byte("ustar") // В заголовке tar сигнатура стоит не в начале, а по смещению tarMagicOffset
+)
+
+const tarMagicOffset = 257
+
+// memberSep отделяет имя архива от имени файла внутри него: archive.tar:logs/app.log:12:строка
+const memberSep = ":"
+
+// decompress возвращает распакованный поток, если br сжат gzip, bzip2 или zstd, и сам br в остальных случаях.
+// compressed сообщает, было ли сжатие. Закрывать нужно только распаковщик: исходный поток закрывает тот, кто его открыл
+func decompress(br *bufio.Reader) (rc io.ReadCloser, compressed bool, err error) {
+	head, _ := br.Peek(len(magicZstd)) // Ошибку чтения вернёт первое же чтение из потока
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		rc, err = gzip.NewReader(br) // Склеенные gzip-потоки (cat a.gz b.gz) читаются подряд
+		return rc, true, err
+	case bytes.HasPrefix(head, magicBzip2):
+		return ioutil.NopCloser(bzip2.NewReader(br)), true, nil
+	case bytes.HasPrefix(head, magicZstd):
+		dec, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1)) // Файлы и так просматриваются параллельно
+		if err != nil {
+			return nil, true, err
+		}
+		return dec.IOReadCloser(), true, nil
+	}
+	return ioutil.NopCloser(br), false, nil
+}
+
+// searchInput ищет в источнике с учётом -z и --archives: распаковывает его
+// и, если это архив, ищет в каждом файле архива по отдельности.
+// Возвращает итог по каждому просмотренному источнику - по самому файлу или по файлам архива
+func (s *Searcher) searchInput(path, name string, r io.Reader, w io.Writer) []result {
+	br := bufio.NewReader(r)
+	in := io.Reader(br)
+	compressed := false
+	if s.cfg.Decompress {
+		rc, ok, err := decompress(br)
+		if err != nil {
+			return []result{{path: path, err: err}}
+		}
+		defer rc.Close()
+		in, compressed = rc, ok
+	}
+	if !s.cfg.Archives {
+		res, err := s.Search(name, in, w)
+		return []result{{path: path, res: res, err: err}}
+	}
+
+	abr := bufio.NewReader(in)
+	head, _ := abr.Peek(tarMagicOffset + len(magicTar))
+	switch {
+	case bytes.HasPrefix(head, magicZip):
+		if ra, size, ok := readerAt(r); ok && !compressed { // Обычный файл читаем с произвольного места, не загружая в память
+			return s.searchZip(name, ra, size, w)
+		}
+		data, err := ioutil.ReadAll(abr) // Оглавление zip лежит в конце - поток придётся прочитать целиком
+		if err != nil {
+			return []result{{path: path, err: err}}
+		}
+		return s.searchZip(name, bytes.NewReader(data), int64(len(data)), w)
+	case len(head) > tarMagicOffset && bytes.HasPrefix(head[tarMagicOffset:], magicTar):
+		return s.searchTar(name, abr, w)
+	}
+	res, err := s.Search(name, abr, w)
+	return []result{{path: path, res: res, err: err}}
+}
+
+// readerAt возвращает r как io.ReaderAt вместе с размером, если r - обычный файл
+func readerAt(r io.Reader) (io.ReaderAt, int64, bool) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return nil, 0, false
+	}
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, 0, false
+	}
+	return f, info.Size(), true
+}
+
+// searchTar ищет в каждом обычном файле tar-архива. Вложенные архивы не раскрываются, но сжатые файлы при -z распаковываются
+func (s *Searcher) searchTar(name string, r io.Reader, w io.Writer) []result {
+	var results []result
+	members := s.memberOutput(w)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return results
+		}
+		if err != nil { // Архив повреждён - дальше его не прочитать
+			return append(results, result{path: name, err: err})
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		results = append(results, s.searchMember(name+memberSep+hdr.Name, tr, members.Next()))
+	}
+}
+
+// searchZip ищет в каждом файле zip-архива
+func (s *Searcher) searchZip(name string, ra io.ReaderAt, size int64, w io.Writer) []result {
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return []result{{path: name, err: err}}
+	}
+	var results []result
+	members := s.memberOutput(w)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		member := name + memberSep + f.Name
+		rc, err := f.Open()
+		if err != nil {
+			results = append(results, result{path: member, err: err})
+			continue
+		}
+		results = append(results, s.searchMember(member, rc, members.Next()))
+		rc.Close()
+	}
+	return results
+}
+
+// searchMember ищет в одном файле архива
+func (s *Searcher) searchMember(name string, r io.Reader, w io.Writer) result {
+	in := io.Reader(r)
+	if s.cfg.Decompress {
+		rc, _, err := decompress(bufio.NewReader(r))
+		if err != nil {
+			return result{path: name, err: err}
+		}
+		defer rc.Close()
+		in = rc
+	}
+	res, err := s.Search(name, in, w)
+	return result{path: name, res: res, err: err}
+}
+
+// memberOutput разделяет вывод файлов одного архива так же, как вывод разных файлов
+func (s *Searcher) memberOutput(w io.Writer) *Output {
+	return &Output{w: w, separate: s.cfg.withContext(), color: s.cfg.Color}
+}
@@ -0,0 +1,188 @@
+package grep
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const logText = "старт\nошибка диска\nконец\n"
+
+func gzipData(t *testing.T, data []byte) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zstdData(t *testing.T, data []byte) []byte {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil)
+}
+
+// tarData собирает tar-архив из пар имя, содержимое
+func tarData(t *testing.T, members ...string) []byte {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for i := 0; i < len(members); i += 2 {
+		hdr := &tar.Header{Name: members[i], Mode: 0o644, Size: int64(len(members[i+1])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(members[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// zipData собирает zip-архив из пар имя, содержимое
+func zipData(t *testing.T, members ...string) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for i := 0; i < len(members); i += 2 {
+		w, err := zw.Create(members[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(members[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestSearchCompressed(t *testing.T) {
+	bz2, err := ioutil.ReadFile("testdata/log.bz2") // Пакет compress/bzip2 умеет только распаковывать
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		data     func(t *testing.T) []byte
+		cfg      Config
+		want     string
+		reported []string
+	}{
+		{
+			name:     "gzip",
+			data:     func(t *testing.T) []byte { return gzipData(t, []byte(logText)) },
+			cfg:      Config{Patterns: []string{"ошибка"}, LineNum: true, Decompress: true},
+			want:     "2:ошибка диска\n",
+			reported: []string{"f"},
+		},
+		{
+			name:     "bzip2",
+			data:     func(*testing.T) []byte { return bz2 },
+			cfg:      Config{Patterns: []string{"ошибка"}, Decompress: true, Count: true},
+			want:     "1\n",
+			reported: []string{"f"},
+		},
+		{
+			name:     "zstd",
+			data:     func(t *testing.T) []byte { return zstdData(t, []byte(logText)) },
+			cfg:      Config{Patterns: []string{"конец"}, Decompress: true, After: 1},
+			want:     "конец\n",
+			reported: []string{"f"},
+		},
+		{
+			name:     "несжатый файл при -z",
+			data:     func(*testing.T) []byte { return []byte(logText) },
+			cfg:      Config{Patterns: []string{"старт"}, Decompress: true},
+			want:     "старт\n",
+			reported: []string{"f"},
+		},
+		{
+			name:     "без -z сжатый файл двоичный",
+			data:     func(t *testing.T) []byte { return gzipData(t, []byte(logText)) },
+			cfg:      Config{Patterns: []string{"."}},
+			reported: []string{"f"},
+		},
+		{
+			name: "tar",
+			data: func(t *testing.T) []byte {
+				return tarData(t, "a.log", "мышь\nкот\n", "logs/b.log", "пёс\nкот\n", "c.log", "мышь\n")
+			},
+			cfg:      Config{Patterns: []string{"кот"}, LineNum: true, WithFilename: true, Archives: true, Before: 1},
+			want:     "f:a.log-1-мышь\nf:a.log:2:кот\n--\nf:logs/b.log-1-пёс\nf:logs/b.log:2:кот\n",
+			reported: []string{"f:a.log", "f:logs/b.log", "f:c.log"},
+		},
+		{
+			name: "tar.gz со сжатым файлом внутри",
+			data: func(t *testing.T) []byte {
+				return gzipData(t, tarData(t, "app.log", "ок\n", "app.log.1.gz", string(gzipData(t, []byte(logText)))))
+			},
+			cfg:      Config{Patterns: []string{"диск"}, WithFilename: true, Archives: true, Decompress: true},
+			want:     "f:app.log.1.gz:ошибка диска\n",
+			reported: []string{"f:app.log", "f:app.log.1.gz"},
+		},
+		{
+			name: "zip",
+			data: func(t *testing.T) []byte {
+				return zipData(t, "dir/", "", "dir/a.txt", "кот\n", "b.txt", "мышь\n")
+			},
+			cfg:      Config{Patterns: []string{"мышь"}, Archives: true, ListNonMatching: true},
+			want:     "f:dir/a.txt\n",
+			reported: []string{"f:dir/a.txt", "f:b.txt"},
+		},
+		{
+			name:     "сжатый zip",
+			data:     func(t *testing.T) []byte { return zstdData(t, zipData(t, "a.txt", "мышь\n")) },
+			cfg:      Config{Patterns: []string{"мышь"}, Archives: true, Decompress: true, WithFilename: true},
+			want:     "f:a.txt:мышь\n",
+			reported: []string{"f:a.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, "f")
+			if err := ioutil.WriteFile(path, tt.data(t), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			s := mustNew(t, tt.cfg)
+			for _, workers := range []int{1, 2} {
+				var out bytes.Buffer
+				var reported []string
+				err := s.SearchFiles([]string{path}, WalkConfig{}, workers, openFile, s.NewOutput(&out),
+					func(path string, res Result, err error) {
+						if err != nil {
+							t.Fatal(err)
+						}
+						reported = append(reported, strings.TrimPrefix(path, root+string(filepath.Separator)))
+					})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.ReplaceAll(out.String(), root+string(filepath.Separator), ""); got != tt.want {
+					t.Errorf("j=%d: вывод %q, ожидался %q", workers, got, tt.want)
+				}
+				if strings.Join(reported, ",") != strings.Join(tt.reported, ",") {
+					t.Errorf("j=%d: итоги по %v, ожидались по %v", workers, reported, tt.reported)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package grep
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+var (
+	errNegativeContext = errors.New("количество строк контекста должно быть неотрицательным")
+	errBadPattern      = errors.New("неверный шаблон")
+)
+
+// BinaryMode - как обращаться с двоичными файлами (--binary-files)
+type BinaryMode int
+
+const (
+	BinaryMatches      BinaryMode = iota // По умолчанию: вместо строк сообщать, что в двоичном файле есть совпадение
+	BinaryText                           // -a: считать двоичный файл текстом
+	BinaryWithoutMatch                   // -I: считать, что в двоичном файле совпадений нет
+)
+
+// Config - все параметры поиска в одном месте. Флаги не выполняются по очереди,
+// каждый лишь меняет поле конфигурации, а сочетание полей разбирает один движок (Searcher)
+type Config struct {
+	Patterns   []string // Искомые шаблоны (-e, -f): строка выбирается, если совпал любой из них
+	Syntax     Syntax   // -G, -E, -F, -P: диалект шаблонов
+	WordRegexp bool     // -w: совпадение должно быть целым словом
+	LineRegexp bool     // -x: совпадение должно занимать всю строку
+	IgnoreCase bool     // -i: игнорировать регистр
+	Invert     bool     // -v: выбирать строки БЕЗ совпадения
+	LineNum    bool     // -n: печатать номер строки
+	Count      bool     // -c: вместо строк печатать их количество
+	Before     int      // -B: сколько строк печатать до совпадения
+	After      int      // -A: сколько строк печатать после совпадения
+
+	WithFilename    bool       // -H: печатать перед каждой строкой имя файла
+	ListMatching    bool       // -l: печатать только имена файлов с совпадениями
+	ListNonMatching bool       // -L: печатать только имена файлов без совпадений
+	Binary          BinaryMode // Обработка двоичных файлов
+
+	Color        bool // --color: подсвечивать совпадения и префиксы ANSI-последовательностями
+	OnlyMatching bool // -o: печатать только совпавшие части строк, каждую с новой строки
+	MaxCount     int  // -m: остановиться после стольких выбранных строк, 0 - без ограничения
+	ByteOffset   bool // -b: печатать смещение в байтах от начала файла
+	JSON         bool // --json: печатать по JSON-объекту на каждую выбранную строку
+
+	Decompress bool // -z: распаковывать файлы, сжатые gzip, bzip2 или zstd
+	Archives   bool // --archives: искать в файлах внутри архивов tar и zip (сжатых tar - вместе с -z)
+}
+
+// listing сообщает, что печатаются только имена файлов
+func (c Config) listing() bool {
+	return c.ListMatching || c.ListNonMatching
+}
+
+// quiet сообщает, что сами строки не печатаются
+func (c Config) quiet() bool {
+	return c.Count || c.listing()
+}
+
+// newMatcher создаёт Matcher в соответствии с конфигурацией.
+// Строки без -i, -w и -x ищутся без регулярных выражений: одна - через bytes.Index, несколько - автоматом Ахо-Корасик.
+// Всё остальное сводится к одному регулярному выражению-альтернативе из всех шаблонов
+func (c Config) newMatcher() (Matcher, error) {
+	if c.Syntax == SyntaxFixed && !c.IgnoreCase && !c.WordRegexp && !c.LineRegexp {
+		switch len(c.Patterns) {
+		case 0:
+			return nothingMatcher{}, nil
+		case 1:
+			return NewFixedMatcher(c.Patterns[0], false)
+		default:
+			return newAhoCorasick(c.Patterns), nil
+		}
+	}
+	if len(c.Patterns) == 0 { // Пустой файл шаблонов (-f): не совпадает ничего
+		return nothingMatcher{}, nil
+	}
+
+	alts := make([]string, len(c.Patterns))
+	for i, p := range c.Patterns {
+		var err error
+		switch c.Syntax {
+		case SyntaxFixed:
+			alts[i] = regexp.QuoteMeta(p)
+		case SyntaxBasic, SyntaxExtended:
+			alts[i], err = translate(p, c.Syntax == SyntaxBasic)
+		default:
+			alts[i] = p
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Каждый шаблон проверяем отдельно: в ошибке разбора общего выражения была бы не строка пользователя, а обёртка (?:...)|(?:...)
+		if _, err := syntax.Parse(alts[i], syntax.Perl); err != nil {
+			var syntaxErr *syntax.Error
+			if errors.As(err, &syntaxErr) {
+				return nil, fmt.Errorf("%w %q: %s", errBadPattern, p, syntaxErr.Code)
+			}
+			return nil, err
+		}
+	}
+	expr := "(?:" + strings.Join(alts, ")|(?:") + ")"
+	if c.LineRegexp {
+		expr = "^(?:" + expr + ")$"
+	}
+	if c.IgnoreCase {
+		expr = "(?i)" + expr // Флаг i в самом выражении - то же, что и приведение к нижнему регистру, но без копирования каждой строки
+	}
+	longest := c.Syntax != SyntaxPerl     // POSIX требует самое длинное из самых левых совпадений, Perl - первое найденное
+	word := c.WordRegexp && !c.LineRegexp // При -x граница слова и так совпадает с краями строки
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	if word || hasWordMarks(re) {
+		return newCheckedMatcher(expr, word, longest)
+	}
+	if longest {
+		re.Longest()
+	}
+	return &regexpMatcher{re: re}, nil
+}
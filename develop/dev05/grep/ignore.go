@@ -0,0 +1,137 @@
+package grep
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const gitignoreName = ".gitignore"
+
+// ignoreRule - одно правило из .gitignore, переведённое в регулярное выражение
+type ignoreRule struct {
+	base    string // Каталог с файлом .gitignore (относительно корня обхода), правило действует внутри него
+	re      *regexp.Regexp
+	negate  bool // Правило начинается с "!" - возвращает ранее исключённый путь
+	dirOnly bool // Правило заканчивается на "/" - касается только каталогов
+}
+
+// ignoreRules - правила всех .gitignore от корня обхода до текущего каталога.
+// Как и в git, побеждает последнее подходящее правило
+type ignoreRules []ignoreRule
+
+// ignored сообщает, исключён ли путь rel (относительно корня обхода)
+func (rules ignoreRules) ignored(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" { // Правило из вложенного .gitignore проверяем относительно его каталога
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = rel[len(rule.base)+1:]
+		}
+		if rule.re.MatchString(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// load дочитывает правила из dir/.gitignore (если такой файл есть) и возвращает расширенный набор.
+// Исходный слайс не меняется: правила вложенного каталога не должны влиять на соседние
+func (rules ignoreRules) load(dir, base string) ignoreRules {
+	f, err := os.Open(filepath.Join(dir, gitignoreName))
+	if err != nil {
+		return rules
+	}
+	defer f.Close()
+
+	loaded := append(ignoreRules{}, rules...)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text(), filepath.ToSlash(base)); ok {
+			loaded = append(loaded, rule)
+		}
+	}
+	return loaded
+}
+
+// parseIgnoreRule переводит строку .gitignore в правило. ok == false для пустых строк, комментариев и ошибочных шаблонов
+func parseIgnoreRule(line, base string) (ignoreRule, bool) {
+	rule := ignoreRule{base: base}
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) { // Экранированные "!" и "#" в начале - обычные символы
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	anchored := strings.Contains(line, "/") // Шаблон со "/" в начале или середине привязан к каталогу .gitignore
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return rule, false
+	}
+
+	expr := globToRegexp(line)
+	if !anchored { // Шаблон без "/" подходит для имени на любой глубине
+		expr = "(.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp переводит шаблон в стиле .gitignore ("*", "?", "[...]", "**") в регулярное выражение
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"): // "**/" - любое количество каталогов, в том числе ни одного
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"): // "**" в конце - всё внутри
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 { // Незакрытая скобка - обычный символ
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package grep
+
+import (
+	"bytes"
+	"regexp"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Matcher сообщает, есть ли в строке совпадение с шаблоном.
+// Строка передаётся без завершающего перевода строки
+type Matcher interface {
+	Match(line []byte) bool
+	// FindAll возвращает границы [начало, конец) всех непересекающихся совпадений в строке слева направо.
+	// Нужен лишь для подсветки, -o и JSON, поэтому вызывается только для выводимых строк
+	FindAll(line []byte) [][]int
+}
+
+// regexpMatcher ищет совпадение с регулярным выражением.
+// Выражение компилируется ОДИН раз при создании, а не на каждой строке
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+// NewRegexpMatcher компилирует шаблон и возвращает Matcher на его основе
+func NewRegexpMatcher(pattern string, ignoreCase bool) (Matcher, error) {
+	if ignoreCase {
+		pattern = "(?i)" + pattern // Флаг i в самом выражении - то же, что и приведение к нижнему регистру, но без копирования каждой строки
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &regexpMatcher{re: re}, nil
+}
+
+func (m *regexpMatcher) Match(line []byte) bool {
+	return m.re.Match(line)
+}
+
+func (m *regexpMatcher) FindAll(line []byte) [][]int {
+	return m.re.FindAllIndex(line, -1)
+}
+
+// fixedMatcher ищет шаблон как обычную подстроку, без интерпретации спецсимволов
+type fixedMatcher struct {
+	pattern []byte
+}
+
+// NewFixedMatcher возвращает Matcher, ищущий строку pattern "как есть"
+func NewFixedMatcher(pattern string, ignoreCase bool) (Matcher, error) {
+	if ignoreCase { // Регистронезависимое сравнение юникода проще и надёжнее доверить пакету regexp
+		return NewRegexpMatcher(regexp.QuoteMeta(pattern), true)
+	}
+	return &fixedMatcher{pattern: []byte(pattern)}, nil
+}
+
+func (m *fixedMatcher) Match(line []byte) bool {
+	return bytes.Contains(line, m.pattern)
+}
+
+func (m *fixedMatcher) FindAll(line []byte) [][]int {
+	if len(m.pattern) == 0 {
+		return nil
+	}
+	var spans [][]int
+	for start := 0; ; {
+		i := bytes.Index(line[start:], m.pattern)
+		if i < 0 {
+			return spans
+		}
+		start += i
+		spans = append(spans, []int{start, start + len(m.pattern)})
+		start += len(m.pattern)
+	}
+}
+
+// nothingMatcher не совпадает ни с чем: так ведёт себя grep с пустым списком шаблонов (-f /dev/null)
+type nothingMatcher struct{}
+
+func (nothingMatcher) Match([]byte) bool { return false }
+
+func (nothingMatcher) FindAll([]byte) [][]int { return nil }
+
+// checkedMatcher ищет совпадения с условиями, которые RE2 проверить не может: целые слова (-w) и границы слова
+// (\<, \>, \b, \B) с буквами юникода. Выражение находит кандидата, а условия проверяются по соседним символам.
+// Если проверка не прошла, поиск продолжается с символа правее начала кандидата
+type checkedMatcher struct {
+	re    *regexp.Regexp // При -w - с обязательной границей справа, само совпадение тогда в группе 1
+	rest  *regexp.Regexp // То же выражение, но "^" и "\A" в нём не совпадают: им ищем не с начала строки
+	group int            // Номер группы с самим совпадением: 1 при -w, иначе 0 (всё совпадение)
+	word  bool           // -w: слева от совпадения не должно быть буквы слова
+	marks []wordMark     // Для каждой группы выражения: какую границу слова проверить в её позиции
+}
+
+// wordMark - граница слова, которую нужно проверить в позиции группы-метки (см. translate)
+type wordMark int
+
+const (
+	markNone wordMark = iota
+	markStart
+	markEnd
+	markBound
+	markNotBound
+)
+
+func newCheckedMatcher(expr string, word, longest bool) (Matcher, error) {
+	m := &checkedMatcher{word: word}
+	if word {
+		// Границу справа проверяет само выражение: так оно при необходимости выберет более короткий вариант совпадения.
+		// Границу слева проверяем вручную, иначе поглощённый разделитель не дал бы найти соседнее слово
+		expr = `(` + expr + `)(?:[^\pL\pN_]|$)`
+		m.group = 1
+	}
+	var err error
+	if m.re, err = regexp.Compile(expr); err != nil {
+		return nil, err
+	}
+	if m.rest, err = withoutStartAnchors(expr); err != nil {
+		return nil, err
+	}
+	if longest {
+		m.re.Longest()
+		m.rest.Longest()
+	}
+	m.marks = wordMarks(m.re)
+	return m, nil
+}
+
+// wordMarks возвращает для каждой группы выражения границу слова, которую она отмечает
+func wordMarks(re *regexp.Regexp) []wordMark {
+	names := re.SubexpNames()
+	marks := make([]wordMark, len(names))
+	for i, name := range names {
+		switch name {
+		case markWordStart:
+			marks[i] = markStart
+		case markWordEnd:
+			marks[i] = markEnd
+		case markWordBound:
+			marks[i] = markBound
+		case markNotWordBound:
+			marks[i] = markNotBound
+		}
+	}
+	return marks
+}
+
+// hasWordMarks сообщает, есть ли в выражении группы-метки границ слова
+func hasWordMarks(re *regexp.Regexp) bool {
+	for _, mark := range wordMarks(re) {
+		if mark != markNone {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutStartAnchors компилирует копию выражения, в которой "^" и "\A" не совпадают никогда
+// (в однострочном режиме "^" - тоже начало текста). Срез строки начинается с её середины, и там якорь начала совпадать не должен
+func withoutStartAnchors(expr string) (*regexp.Regexp, error) {
+	tree, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	dropStartAnchors(tree)
+	return regexp.Compile(tree.String())
+}
+
+func dropStartAnchors(re *syntax.Regexp) {
+	if re.Op == syntax.OpBeginText || re.Op == syntax.OpBeginLine {
+		re.Op = syntax.OpNoMatch
+	}
+	for _, sub := range re.Sub {
+		dropStartAnchors(sub)
+	}
+}
+
+func (m *checkedMatcher) Match(line []byte) bool {
+	_, ok := m.next(line, 0)
+	return ok
+}
+
+func (m *checkedMatcher) FindAll(line []byte) [][]int {
+	var spans [][]int
+	for pos := 0; pos <= len(line); {
+		span, ok := m.next(line, pos)
+		if !ok {
+			break
+		}
+		spans = append(spans, span)
+		if span[1] > span[0] {
+			pos = span[1]
+		} else {
+			pos = span[1] + 1
+		}
+	}
+	return spans
+}
+
+// next ищет первое подходящее совпадение, начинающееся не раньше pos
+func (m *checkedMatcher) next(line []byte, pos int) ([]int, bool) {
+	for pos <= len(line) {
+		loc := m.find(line, pos)
+		if loc == nil {
+			return nil, false
+		}
+		start, end := loc[2*m.group], loc[2*m.group+1]
+		if (!m.word || !wordBefore(line, start)) && m.marksHold(line, loc) {
+			return []int{start, end}, true
+		}
+		_, size := utf8.DecodeRune(line[start:]) // Пробуем начать на символ правее
+		if size == 0 {
+			return nil, false
+		}
+		pos = start + size
+	}
+	return nil, false
+}
+
+// find ищет совпадение в строке, начиная с pos, и возвращает индексы групп относительно всей строки
+func (m *checkedMatcher) find(line []byte, pos int) []int {
+	if pos == 0 {
+		return m.re.FindSubmatchIndex(line)
+	}
+	loc := m.rest.FindSubmatchIndex(line[pos:])
+	for i := range loc {
+		if loc[i] >= 0 {
+			loc[i] += pos
+		}
+	}
+	return loc
+}
+
+// marksHold проверяет границы слова в позициях групп-меток, участвовавших в совпадении
+func (m *checkedMatcher) marksHold(line []byte, loc []int) bool {
+	for group, mark := range m.marks {
+		pos := loc[2*group]
+		if mark == markNone || pos < 0 {
+			continue
+		}
+		before, after := wordBefore(line, pos), wordAfter(line, pos)
+		switch {
+		case mark == markStart && (before || !after),
+			mark == markEnd && (!before || after),
+			mark == markBound && before == after,
+			mark == markNotBound && before != after:
+			return false
+		}
+	}
+	return true
+}
+
+// wordBefore сообщает, стоит ли перед позицией pos буква слова
+func wordBefore(line []byte, pos int) bool {
+	r, size := utf8.DecodeLastRune(line[:pos])
+	return size > 0 && isWordRune(r)
+}
+
+// wordAfter сообщает, начинается ли с позиции pos буква слова
+func wordAfter(line []byte, pos int) bool {
+	r, size := utf8.DecodeRune(line[pos:])
+	return size > 0 && isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+}
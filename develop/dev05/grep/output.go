@@ -0,0 +1,44 @@
+package grep
+
+import "io"
+
+// Output сводит вывод нескольких источников в один поток.
+// Как и GNU grep, ставит "--" между группами контекста из разных файлов
+type Output struct {
+	w        io.Writer
+	separate bool // Нужен ли разделитель между файлами (только если печатается контекст)
+	color    bool
+	printed  bool // Был ли уже вывод от предыдущих источников
+}
+
+// NewOutput создаёт Output для результатов этого Searcher
+func (s *Searcher) NewOutput(w io.Writer) *Output {
+	return &Output{w: w, separate: s.cfg.withContext(), color: s.cfg.Color}
+}
+
+// Next возвращает io.Writer для очередного источника
+func (o *Output) Next() io.Writer {
+	return &sourceWriter{out: o}
+}
+
+// sourceWriter перед первой записью очередного источника дописывает разделитель, если до него уже что-то печаталось
+type sourceWriter struct {
+	out   *Output
+	wrote bool
+}
+
+func (sw *sourceWriter) Write(p []byte) (int, error) {
+	if !sw.wrote && len(p) > 0 {
+		if sw.out.separate && sw.out.printed {
+			sep := groupSeparator
+			if sw.out.color {
+				sep = sgrStart(colorSep) + sep + sgrEnd
+			}
+			if _, err := io.WriteString(sw.out.w, sep+"\n"); err != nil {
+				return 0, err
+			}
+		}
+		sw.wrote, sw.out.printed = true, true
+	}
+	return sw.out.w.Write(p)
+}
@@ -0,0 +1,136 @@
+package grep
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+var errStopped = errors.New("поиск остановлен")
+
+// Opener открывает источник по пути из Walk ("-" - стандартный ввод).
+// Возвращает имя для вывода и сам поток
+type Opener func(path string) (name string, r io.ReadCloser, err error)
+
+// Reporter получает итог по каждому файлу - строго в порядке обхода.
+// Для архива (--archives) итог приходит по каждому файлу внутри него, path тогда - "архив:файл".
+// err - ошибка доступа к файлу или чтения, поиск в остальных файлах продолжается
+type Reporter func(path string, res Result, err error)
+
+// result - итог поиска в одном источнике. У архива (--archives) их несколько - по одному на каждый файл внутри
+type result struct {
+	path string
+	res  Result
+	err  error
+}
+
+// job - поиск в одном файле. Вывод копится в buf, пока не подойдёт очередь файла
+type job struct {
+	path    string
+	err     error // Ошибка обхода: такой файл не открываем
+	results []result
+	buf     bytes.Buffer
+	done    chan struct{} // Закрывается, когда поиск в файле завершён
+}
+
+// SearchFiles ищет во всех файлах, которые перечисляет Walk(paths, walk).
+// Файлы просматриваются в workers горутин одновременно, но вывод каждого файла попадает в out
+// целиком и строго в порядке обхода, поэтому результат не отличается от последовательного поиска.
+// При workers <= 1 вывод не буферизуется, а сразу пишется в out
+func (s *Searcher) SearchFiles(paths []string, walk WalkConfig, workers int, open Opener, out *Output, report Reporter) error {
+	if workers <= 1 {
+		return Walk(paths, walk, func(path string, err error) error {
+			j := &job{path: path, err: err}
+			s.run(j, open, out.Next())
+			j.report(report)
+			return nil
+		})
+	}
+
+	var (
+		jobs    = make(chan *job)            // Очередь для рабочих горутин
+		ordered = make(chan *job, workers*2) // Те же задания в порядке обхода; ёмкость ограничивает, сколько вывода копится в памяти
+		stop    = make(chan struct{})        // Закрывается, если писать результат дальше некуда
+		walkErr = make(chan error, 1)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				s.run(j, open, &j.buf)
+				close(j.done)
+			}
+		}()
+	}
+	go func() { // Обход файлов: каждый путь сначала занимает место в очереди вывода, затем уходит рабочим
+		defer close(ordered)
+		defer close(jobs)
+		walkErr <- Walk(paths, walk, func(path string, err error) error {
+			j := &job{path: path, err: err, done: make(chan struct{})}
+			select {
+			case ordered <- j:
+			case <-stop:
+				return errStopped
+			}
+			select {
+			case jobs <- j:
+			case <-stop: // Задание уже в очереди вывода - отмечаем его выполненным, иначе её не дочитать
+				close(j.done)
+				return errStopped
+			}
+			return nil
+		})
+	}()
+
+	var writeErr error
+	for j := range ordered { // Выводим результаты в порядке обхода, дожидаясь каждого файла
+		<-j.done
+		if writeErr != nil { // После ошибки записи лишь дочитываем очередь, чтобы все горутины завершились
+			continue
+		}
+		if _, err := j.buf.WriteTo(out.Next()); err != nil {
+			writeErr = err
+			close(stop)
+			continue
+		}
+		j.report(report)
+	}
+	wg.Wait()
+	if writeErr != nil {
+		return writeErr
+	}
+	if err := <-walkErr; err != errStopped {
+		return err
+	}
+	return nil
+}
+
+// run выполняет поиск по заданию и записывает итог в него же
+func (s *Searcher) run(j *job, open Opener, w io.Writer) {
+	if j.err != nil {
+		j.results = []result{{path: j.path, err: j.err}}
+		return
+	}
+	name, r, err := open(j.path)
+	if err != nil {
+		j.results = []result{{path: j.path, err: err}}
+		return
+	}
+	defer r.Close()
+	if s.cfg.Decompress || s.cfg.Archives {
+		j.results = s.searchInput(j.path, name, r, w)
+		return
+	}
+	res, err := s.Search(name, r, w)
+	j.results = []result{{path: j.path, res: res, err: err}}
+}
+
+// report передаёт итоги задания в Reporter
+func (j *job) report(report Reporter) {
+	for _, r := range j.results {
+		report(r.path, r.res, r.err)
+	}
+}
@@ -0,0 +1,123 @@
+package grep
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeCorpus создаёт files файлов по lines строк, в каждой десятой строке есть слово "мышь".
+// Возвращает корневой каталог и общий размер файлов
+func makeCorpus(tb testing.TB, files, lines int) (string, int64) {
+	tb.Helper()
+	root := tb.TempDir()
+	var total int64
+	for i := 0; i < files; i++ {
+		var b strings.Builder
+		for j := 0; j < lines; j++ {
+			if j%10 == 0 {
+				fmt.Fprintf(&b, "файл %d строка %d: мышь бежит по полю\n", i, j)
+			} else {
+				fmt.Fprintf(&b, "файл %d строка %d: стол рука чашка дом солнце игра\n", i, j)
+			}
+		}
+		path := filepath.Join(root, fmt.Sprintf("dir%02d", i%7), fmt.Sprintf("file%04d.txt", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		total += int64(b.Len())
+	}
+	return root, total
+}
+
+func openFile(path string) (string, io.ReadCloser, error) {
+	f, err := os.Open(path)
+	return path, f, err
+}
+
+func searchFiles(tb testing.TB, s *Searcher, root string, workers int, w io.Writer) []string {
+	tb.Helper()
+	var reported []string
+	err := s.SearchFiles([]string{root}, WalkConfig{Recursive: true}, workers, openFile, s.NewOutput(w),
+		func(path string, res Result, err error) {
+			if err != nil {
+				tb.Fatal(err)
+			}
+			reported = append(reported, path)
+		})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return reported
+}
+
+func TestSearchFilesOrdered(t *testing.T) {
+	root, _ := makeCorpus(t, 40, 50)
+	s := mustNew(t, Config{Patterns: []string{"мышь"}, WithFilename: true, LineNum: true, Before: 1})
+
+	var sequential bytes.Buffer
+	expectedOrder := searchFiles(t, s, root, 1, &sequential)
+	if len(expectedOrder) != 40 {
+		t.Fatalf("просмотрено %d файлов, ожидалось 40", len(expectedOrder))
+	}
+
+	for _, workers := range []int{2, 4, 16, 100} {
+		t.Run(fmt.Sprintf("j=%d", workers), func(t *testing.T) {
+			var parallel bytes.Buffer
+			order := searchFiles(t, s, root, workers, &parallel)
+			if parallel.String() != sequential.String() {
+				t.Error("вывод параллельного поиска отличается от последовательного")
+			}
+			if strings.Join(order, "\n") != strings.Join(expectedOrder, "\n") {
+				t.Errorf("порядок файлов %v, ожидался %v", order, expectedOrder)
+			}
+		})
+	}
+}
+
+// failingWriter перестаёт принимать запись после limit байт
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, io.ErrShortWrite
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestSearchFilesWriteError(t *testing.T) {
+	root, _ := makeCorpus(t, 30, 20)
+	s := mustNew(t, Config{Patterns: []string{"мышь"}})
+	err := s.SearchFiles([]string{root}, WalkConfig{Recursive: true}, 4, openFile, s.NewOutput(&failingWriter{limit: 500}),
+		func(string, Result, error) {})
+	if err != io.ErrShortWrite {
+		t.Errorf("ошибка %v, ожидалась %v", err, io.ErrShortWrite)
+	}
+}
+
+func BenchmarkSearchFiles(b *testing.B) {
+	root, total := makeCorpus(b, 200, 2000)
+	s, err := New(Config{Patterns: []string{"мышь.*пол"}, WithFilename: true, LineNum: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("j=%d", workers), func(b *testing.B) {
+			b.SetBytes(total) // go test -bench выведет пропускную способность в МБ/с
+			for i := 0; i < b.N; i++ {
+				searchFiles(b, s, root, workers, ioutil.Discard)
+			}
+		})
+	}
+}
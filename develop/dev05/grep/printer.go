@@ -0,0 +1,197 @@
+package grep
+
+import (
+	"bufio"
+	"encoding/json"
+	"strconv"
+)
+
+// Цвета по умолчанию, как в GNU grep (переменная GREP_COLORS не поддерживается)
+const (
+	colorMatch = "01;31"        // Совпадение - жирный красный
+	colorFile  = "35"           // Имя файла - пурпурный
+	colorLine  = "32"           // Номер строки и смещение - зелёный
+	colorSep   = "36"           // Разделители ":", "-", "--" - голубой
+	sgrEnd     = "\033[m\033[K" // Сброс цвета; \033[K стирает хвост строки, чтобы фон не "протекал" при переносе
+)
+
+func sgrStart(code string) string {
+	return "\033[" + code + "m\033[K"
+}
+
+// printer форматирует строки вывода
+type printer struct {
+	w          *bufio.Writer
+	name       []byte // Имя источника
+	withName   bool   // Печатать ли имя перед каждой строкой
+	lineNum    bool
+	byteOffset bool
+	color      bool
+	num        []byte // Буфер под числа, чтобы не выделять память на каждой строке
+}
+
+func newPrinter(w *bufio.Writer, name string, cfg Config) *printer {
+	return &printer{
+		w:          w,
+		name:       []byte(name),
+		withName:   cfg.WithFilename,
+		lineNum:    cfg.LineNum,
+		byteOffset: cfg.ByteOffset,
+		color:      cfg.Color,
+	}
+}
+
+// colored печатает text, обрамляя его ANSI-последовательностями цвета code, если подсветка включена
+func (p *printer) colored(code string, text []byte) error {
+	if p.color {
+		if _, err := p.w.WriteString(sgrStart(code)); err != nil {
+			return err
+		}
+	}
+	if _, err := p.w.Write(text); err != nil {
+		return err
+	}
+	if p.color {
+		_, err := p.w.WriteString(sgrEnd)
+		return err
+	}
+	return nil
+}
+
+// field печатает поле префикса (имя, номер, смещение) и разделитель после него
+func (p *printer) field(code string, text []byte, sep byte) error {
+	if err := p.colored(code, text); err != nil {
+		return err
+	}
+	return p.colored(colorSep, []byte{sep})
+}
+
+// prefix печатает перед строкой то, что требуется: "имя<sep>номер<sep>смещение<sep>"
+func (p *printer) prefix(num int, off int64, sep byte) error {
+	if p.withName {
+		if err := p.field(colorFile, p.name, sep); err != nil {
+			return err
+		}
+	}
+	if p.lineNum {
+		p.num = strconv.AppendInt(p.num[:0], int64(num), 10)
+		if err := p.field(colorLine, p.num, sep); err != nil {
+			return err
+		}
+	}
+	if p.byteOffset {
+		p.num = strconv.AppendInt(p.num[:0], off, 10)
+		if err := p.field(colorLine, p.num, sep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// line печатает строку целиком, подсвечивая участки spans
+func (p *printer) line(num int, off int64, sep byte, line []byte, spans [][]int) error {
+	if err := p.prefix(num, off, sep); err != nil {
+		return err
+	}
+	pos := 0
+	for _, span := range spans {
+		if span[0] == span[1] { // Пустое совпадение подсвечивать нечего
+			continue
+		}
+		if _, err := p.w.Write(line[pos:span[0]]); err != nil {
+			return err
+		}
+		if err := p.colored(colorMatch, line[span[0]:span[1]]); err != nil {
+			return err
+		}
+		pos = span[1]
+	}
+	if _, err := p.w.Write(line[pos:]); err != nil {
+		return err
+	}
+	return p.w.WriteByte('\n')
+}
+
+// only печатает каждое непустое совпадение отдельной строкой (-o); смещение при -b - от начала совпадения
+func (p *printer) only(num int, off int64, line []byte, spans [][]int) error {
+	for _, span := range spans {
+		if span[0] == span[1] {
+			continue
+		}
+		if err := p.prefix(num, off+int64(span[0]), matchSep); err != nil {
+			return err
+		}
+		if err := p.colored(colorMatch, line[span[0]:span[1]]); err != nil {
+			return err
+		}
+		if err := p.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonMatch - запись о выбранной строке в режиме --json
+type jsonMatch struct {
+	File       string         `json:"file"`
+	Line       int            `json:"line"`
+	Column     int            `json:"column,omitempty"` // Номер байта начала первого совпадения, с единицы; нет при -v
+	Offset     int64          `json:"offset"`           // Смещение начала строки от начала файла в байтах
+	Text       string         `json:"text"`
+	Submatches []jsonSubmatch `json:"submatches"`
+}
+
+type jsonSubmatch struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"` // Границы совпадения в байтах от начала строки, [start, end)
+	End   int    `json:"end"`
+}
+
+// json печатает выбранную строку как один JSON-объект в строке вывода
+func (p *printer) json(num int, off int64, line []byte, spans [][]int) error {
+	rec := jsonMatch{File: string(p.name), Line: num, Offset: off, Text: string(line), Submatches: []jsonSubmatch{}}
+	for _, span := range spans {
+		if span[0] == span[1] {
+			continue
+		}
+		if rec.Column == 0 {
+			rec.Column = span[0] + 1
+		}
+		rec.Submatches = append(rec.Submatches, jsonSubmatch{Text: string(line[span[0]:span[1]]), Start: span[0], End: span[1]})
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := p.w.Write(data); err != nil {
+		return err
+	}
+	return p.w.WriteByte('\n')
+}
+
+func (p *printer) count(n int) error {
+	if p.withName {
+		if err := p.field(colorFile, p.name, matchSep); err != nil {
+			return err
+		}
+	}
+	p.num = strconv.AppendInt(p.num[:0], int64(n), 10)
+	p.num = append(p.num, '\n')
+	_, err := p.w.Write(p.num)
+	return err
+}
+
+// filename печатает имя источника (для -l/-L оно печатается всегда, даже без -H)
+func (p *printer) filename() error {
+	if err := p.colored(colorFile, p.name); err != nil {
+		return err
+	}
+	return p.w.WriteByte('\n')
+}
+
+func (p *printer) separator() error {
+	if err := p.colored(colorSep, []byte(groupSeparator)); err != nil {
+		return err
+	}
+	return p.w.WriteByte('\n')
+}
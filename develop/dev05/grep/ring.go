@@ -0,0 +1,60 @@
+package grep
+
+// ring - кольцевой буфер на n последних строк, ещё не попавших в вывод.
+// Нужен для контекста "до" совпадения (-B): держим в памяти не больше n строк, сколько бы ни весил файл
+type ring struct {
+	lines [][]byte // Строки хранятся копиями: буфер bufio.Reader перезаписывается при следующем чтении
+	nums  []int    // Номера строк в исходном тексте
+	offs  []int64  // Смещения начала строк в байтах (для -b)
+	start int      // Индекс самой старой строки
+	size  int      // Сколько строк сейчас в буфере
+}
+
+func newRing(n int) *ring {
+	return &ring{
+		lines: make([][]byte, n),
+		nums:  make([]int, n),
+		offs:  make([]int64, n),
+	}
+}
+
+// push добавляет строку в буфер, вытесняя самую старую, если места нет
+func (r *ring) push(num int, off int64, line []byte) {
+	if len(r.lines) == 0 {
+		return
+	}
+	idx := (r.start + r.size) % len(r.lines)
+	if r.size == len(r.lines) { // Буфер заполнен - перезаписываем самую старую строку
+		idx = r.start
+		r.start = (r.start + 1) % len(r.lines)
+	} else {
+		r.size++
+	}
+	r.lines[idx] = append(r.lines[idx][:0], line...) // Переиспользуем уже выделенную под слот память
+	r.nums[idx] = num
+	r.offs[idx] = off
+}
+
+// first возвращает номер самой старой строки в буфере
+func (r *ring) first() (int, bool) {
+	if r.size == 0 {
+		return 0, false
+	}
+	return r.nums[r.start], true
+}
+
+// each перебирает строки от самой старой к самой новой
+func (r *ring) each(fn func(num int, off int64, line []byte) error) error {
+	for i := 0; i < r.size; i++ {
+		idx := (r.start + i) % len(r.lines)
+		if err := fn(r.nums[idx], r.offs[idx], r.lines[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset опустошает буфер (выделенная память остаётся для повторного использования)
+func (r *ring) reset() {
+	r.start, r.size = 0, 0
+}
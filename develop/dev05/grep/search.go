@@ -0,0 +1,213 @@
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+const (
+	groupSeparator = "--" // Разделитель несмежных групп контекста, как в GNU grep
+	matchSep       = ':'  // После имени файла и номера строки с совпадением
+	contextSep     = '-'  // После имени файла и номера строки контекста
+	binaryPeek     = 32 * 1024
+)
+
+// Result - итог поиска в одном источнике
+type Result struct {
+	Selected int  // Сколько строк выбрано (без учёта строк контекста)
+	Binary   bool // Источник оказался двоичным и в нём есть совпадение - строки не печатались
+}
+
+// Searcher - потоковый движок поиска. Текст читается построчно через bufio.Reader,
+// поэтому в памяти одновременно находятся лишь текущая строка и не более Before строк контекста
+type Searcher struct {
+	cfg     Config
+	matcher Matcher // Шаблон компилируется один раз в New, а не на каждый файл или строку
+}
+
+// New проверяет конфигурацию и создаёт Searcher
+func New(cfg Config) (*Searcher, error) {
+	if cfg.Before < 0 || cfg.After < 0 {
+		return nil, errNegativeContext
+	}
+	matcher, err := cfg.newMatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &Searcher{cfg: cfg, matcher: matcher}, nil
+}
+
+// Search читает r до конца и пишет в w выбранные строки вместе с контекстом
+// (или только их количество, или имя источника - в зависимости от конфигурации).
+// name - имя источника для префиксов вывода
+func (s *Searcher) Search(name string, r io.Reader, w io.Writer) (Result, error) {
+	var res Result
+	br := bufio.NewReaderSize(r, 64*1024)
+	p := newPrinter(bufio.NewWriter(w), name, s.cfg)
+
+	binary := false
+	if s.cfg.Binary != BinaryText { // Как и GNU grep, считаем файл двоичным, если в его начале есть нулевой байт
+		head, _ := br.Peek(binaryPeek) // Peek вернёт ошибку, если файл короче, но и прочитанного достаточно
+		binary = bytes.IndexByte(head, 0) >= 0
+	}
+	if binary && s.cfg.Binary == BinaryWithoutMatch {
+		return res, nil
+	}
+
+	before, after := s.cfg.Before, s.cfg.After
+	if !s.cfg.withContext() {
+		before, after = 0, 0
+	}
+	ring := newRing(before)
+	emit := func(num int, off int64, sep byte, line []byte) error { return s.emit(p, num, off, sep, line) }
+
+	var (
+		line        []byte
+		num         int   // Номер текущей строки
+		off         int64 // Смещение начала текущей строки в байтах
+		next        int64 // Смещение начала следующей строки
+		afterLeft   int   // Сколько строк контекста "после" ещё осталось напечатать
+		lastPrinted int   // Номер последней напечатанной строки, 0 - ещё ничего не печатали
+		maxReached  bool  // Выбрано MaxCount строк: дальше печатается только хвост контекста
+		err         error
+	)
+	for {
+		line, err = readLine(br, line[:0])
+		if err != nil && err != io.EOF {
+			return res, err
+		}
+		if err == io.EOF && len(line) == 0 { // Последняя строка файла уже обработана
+			break
+		}
+		num++
+		off, next = next, next+int64(len(line))+1
+		if !binary && s.cfg.Binary != BinaryText && bytes.IndexByte(line, 0) >= 0 { // Нулевой байт встретился дальше начала файла
+			if s.cfg.Binary == BinaryWithoutMatch { // Остаток файла пропускаем, а уже выбранные строки учитываем:
+				return res, s.finish(p, res) // иначе код возврата и -c/-l/-L противоречили бы напечатанному
+			}
+			binary = true
+		}
+
+		switch {
+		case maxReached: // После -m NUM совпадений строки, даже совпадающие, идут лишь как контекст "после"
+			if afterLeft == 0 || binary {
+				return res, s.finish(p, res)
+			}
+			if err := emit(num, off, contextSep, line); err != nil {
+				return res, err
+			}
+			afterLeft--
+		case s.matcher.Match(line) != s.cfg.Invert: // Строка выбрана (с учётом -v)
+			res.Selected++
+			maxReached = s.cfg.MaxCount > 0 && res.Selected >= s.cfg.MaxCount
+			if s.cfg.listing() { // Для -l/-L достаточно первого совпадения
+				return res, s.finish(p, res)
+			}
+			if s.cfg.Count {
+				if maxReached {
+					return res, s.finish(p, res)
+				}
+				continue
+			}
+			if binary { // Строки двоичного файла не печатаем, о совпадении сообщит вызывающий
+				res.Binary = true
+				return res, p.w.Flush()
+			}
+			first := num
+			if n, ok := ring.first(); ok {
+				first = n
+			}
+			if after+before > 0 && lastPrinted > 0 && first > lastPrinted+1 { // Между группами есть пропущенные строки
+				if err := p.separator(); err != nil {
+					return res, err
+				}
+			}
+			if err := ring.each(func(n int, o int64, l []byte) error { return emit(n, o, contextSep, l) }); err != nil {
+				return res, err
+			}
+			ring.reset()
+			if err := emit(num, off, matchSep, line); err != nil {
+				return res, err
+			}
+			lastPrinted = num
+			afterLeft = after
+		case afterLeft > 0 && !binary: // Строка попадает в контекст "после" предыдущего совпадения
+			if err := emit(num, off, contextSep, line); err != nil {
+				return res, err
+			}
+			lastPrinted = num
+			afterLeft--
+		default: // Может пригодиться как контекст "до" следующего совпадения
+			ring.push(num, off, line)
+		}
+
+		if err == io.EOF { // Последняя строка без завершающего перевода строки
+			break
+		}
+	}
+	return res, s.finish(p, res)
+}
+
+// emit печатает строку в нужном виде: целиком, только совпадения (-o) или JSON-объектом.
+// sep отличает выбранную строку (matchSep) от строки контекста (contextSep)
+func (s *Searcher) emit(p *printer, num int, off int64, sep byte, line []byte) error {
+	switch {
+	case s.cfg.JSON: // В JSON попадают только выбранные строки
+		if sep != matchSep {
+			return nil
+		}
+		var spans [][]int
+		if !s.cfg.Invert {
+			spans = s.matcher.FindAll(line)
+		}
+		return p.json(num, off, line, spans)
+	case s.cfg.OnlyMatching: // При -o строки контекста не печатаются, а в выбранных при -v печатать нечего
+		if sep != matchSep || s.cfg.Invert {
+			return nil
+		}
+		return p.only(num, off, line, s.matcher.FindAll(line))
+	case s.cfg.Color: // Подсвечиваются совпадения в любой выводимой строке (при -v совпадения есть в строках контекста)
+		return p.line(num, off, sep, line, s.matcher.FindAll(line))
+	default:
+		return p.line(num, off, sep, line, nil)
+	}
+}
+
+// finish печатает итог по источнику для -c, -l и -L и сбрасывает буфер вывода
+func (s *Searcher) finish(p *printer, res Result) error {
+	var err error
+	switch {
+	case s.cfg.listing():
+		if s.cfg.ListMatching == (res.Selected > 0) { // -l печатает файлы с совпадениями, -L - без
+			err = p.filename()
+		}
+	case s.cfg.Count:
+		err = p.count(res.Selected)
+	}
+	if err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// withContext сообщает, печатается ли контекст (при -c, -l, -L строки не печатаются вовсе)
+func (c Config) withContext() bool {
+	return !c.quiet() && (c.Before > 0 || c.After > 0)
+}
+
+// readLine дочитывает одну строку из br в dst (без "\n").
+// В отличие от bufio.Scanner, не ограничивает длину строки размером буфера
+func readLine(br *bufio.Reader, dst []byte) ([]byte, error) {
+	for {
+		frag, err := br.ReadSlice('\n')
+		dst = append(dst, frag...)
+		if err == bufio.ErrBufferFull { // Строка длиннее буфера - читаем дальше
+			continue
+		}
+		if err == nil {
+			dst = dst[:len(dst)-1] // Отрезаем "\n"
+		}
+		return dst, err
+	}
+}
@@ -0,0 +1,164 @@
+package grep
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const text = "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
+
+func search(t *testing.T, s *Searcher, input string) (string, int) {
+	t.Helper()
+	var out bytes.Buffer
+	res, err := s.Search("", strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), res.Selected
+}
+
+func mustNew(t *testing.T, cfg Config) *Searcher {
+	t.Helper()
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSearch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		searcher *Searcher
+		input    string
+		expected string
+		selected int
+	}{
+		{
+			name:     "без опций",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь"}}),
+			input:    text,
+			expected: "высоко мышь луч\nмышь его говори\n",
+			selected: 2,
+		},
+		{
+			name:     "after",
+			searcher: mustNew(t, Config{Patterns: []string{"солнце"}, After: 1}),
+			input:    text,
+			expected: "дом солнце игра\nвысоко мышь луч\n",
+			selected: 1,
+		},
+		{
+			name:     "before",
+			searcher: mustNew(t, Config{Patterns: []string{"жук"}, Before: 3}),
+			input:    text,
+			expected: "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\n",
+			selected: 1,
+		},
+		{
+			name:     "context",
+			searcher: mustNew(t, Config{Patterns: []string{"солнце"}, Before: 1, After: 1}),
+			input:    text,
+			expected: "стол рука чашка\nдом солнце игра\nвысоко мышь луч\n",
+			selected: 1,
+		},
+		{
+			name:     "ignore case",
+			searcher: mustNew(t, Config{Patterns: []string{"солНЦЕ"}, IgnoreCase: true, After: 2}),
+			input:    "стол рука чашка\nдом сОлнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори",
+			expected: "дом сОлнце игра\nвысоко мышь луч\nцифра жук зебра\n",
+			selected: 1,
+		},
+		{
+			name:     "invert",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь"}, Invert: true}),
+			input:    text,
+			expected: "стол рука чашка\nдом солнце игра\nцифра жук зебра\n",
+			selected: 3,
+		},
+		{
+			name:     "номера строк и разделитель несмежных групп",
+			searcher: mustNew(t, Config{Patterns: []string{"^[ac]$"}, Before: 1, LineNum: true}),
+			input:    "x\na\ny\nz\nb\nc\n",
+			expected: "1-x\n2:a\n--\n5-b\n6:c\n",
+			selected: 2,
+		},
+		{
+			name:     "перекрывающийся контекст не дублируется",
+			searcher: mustNew(t, Config{Patterns: []string{"a"}, Before: 2, After: 2}),
+			input:    "a\nb\na\nc\nd\nx\ne\nf\na\n",
+			expected: "a\nb\na\nc\nd\n--\ne\nf\na\n",
+			selected: 3,
+		},
+		{
+			name:     "fixed не интерпретирует спецсимволы",
+			searcher: mustNew(t, Config{Patterns: []string{"a.c"}, Syntax: SyntaxFixed}),
+			input:    "abc\na.c\n",
+			expected: "a.c\n",
+			selected: 1,
+		},
+		{
+			name:     "count с invert считает невыбранные строки, контекст не печатается",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь"}, Invert: true, Count: true, After: 1}),
+			input:    text,
+			expected: "3\n",
+			selected: 3,
+		},
+		{
+			name:     "json по объекту на выбранную строку",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь|луч"}, Syntax: SyntaxExtended, JSON: true, After: 1}),
+			input:    "стол\nвысоко мышь луч\nдом",
+			expected: `{"file":"","line":2,"column":14,"offset":9,"text":"высоко мышь луч","submatches":[` +
+				`{"text":"мышь","start":13,"end":21},{"text":"луч","start":22,"end":28}]}` + "\n",
+			selected: 1,
+		},
+		{
+			name:     "json при invert без совпадений внутри строки",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь"}, JSON: true, Invert: true, MaxCount: 1}),
+			input:    "стол\nдом",
+			expected: `{"file":"","line":1,"offset":0,"text":"стол","submatches":[]}` + "\n",
+			selected: 1,
+		},
+		{
+			name:     "пустые совпадения не печатаются при -o",
+			searcher: mustNew(t, Config{Patterns: []string{"ж*"}, OnlyMatching: true}),
+			input:    "абв\nужжи\n",
+			expected: "жж\n",
+			selected: 2,
+		},
+		{
+			name:     "строка длиннее буфера чтения",
+			searcher: mustNew(t, Config{Patterns: []string{"конец$"}}),
+			input:    strings.Repeat("ж", 100000) + "конец\nдругая",
+			expected: strings.Repeat("ж", 100000) + "конец\n",
+			selected: 1,
+		},
+		{
+			name:     "-I: нулевой байт после напечатанных строк",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь"}, Binary: BinaryWithoutMatch}),
+			input:    "мышь один\n" + strings.Repeat("ж\n", binaryPeek) + "мышь два\n\x00\nмышь три\n",
+			expected: "мышь один\nмышь два\n",
+			selected: 2,
+		},
+		{
+			name:     "-I -c: нулевой байт после совпадений",
+			searcher: mustNew(t, Config{Patterns: []string{"мышь"}, Binary: BinaryWithoutMatch, Count: true}),
+			input:    "мышь один\n" + strings.Repeat("ж\n", binaryPeek) + "\x00\nмышь три\n",
+			expected: "1\n",
+			selected: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			actual, selected := search(t, test.searcher, test.input)
+			if actual != test.expected {
+				t.Errorf("результат не совпал с ожидаемым значением: %q, ожидалось %q", actual, test.expected)
+			}
+			if selected != test.selected {
+				t.Errorf("выбрано строк: %d, ожидалось %d", selected, test.selected)
+			}
+		})
+	}
+}
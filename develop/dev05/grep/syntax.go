@@ -0,0 +1,210 @@
+package grep
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errTrailingBackslash = errors.New("шаблон заканчивается обратной косой чертой")
+	errBackReference     = errors.New("обратные ссылки (\\1-\\9) не поддерживаются")
+	errUnmatchedBracket  = errors.New("незакрытая квадратная скобка [")
+	errUnmatchedInterval = errors.New("незакрытый интервал \\{")
+)
+
+// Имена пустых групп-меток, которыми translate заменяет границы слова
+const (
+	markWordStart    = "grepWordStart"    // \< - слева не буква слова, справа буква
+	markWordEnd      = "grepWordEnd"      // \> - слева буква слова, справа нет
+	markWordBound    = "grepWordBound"    // \b - граница слова с любой стороны
+	markNotWordBound = "grepNotWordBound" // \B - не граница слова
+)
+
+// Syntax - диалект шаблона
+type Syntax int
+
+const (
+	SyntaxBasic    Syntax = iota // -G: базовые регулярные выражения POSIX (BRE), по умолчанию, как в GNU grep
+	SyntaxExtended               // -E: расширенные регулярные выражения POSIX (ERE)
+	SyntaxFixed                  // -F: строки без спецсимволов
+	SyntaxPerl                   // -P: Perl-подобный синтаксис в пределах возможностей RE2 (без обратных ссылок и lookaround)
+)
+
+// translate переводит шаблон POSIX BRE (basic == true) или ERE в синтаксис RE2 пакета regexp.
+// Отличия, которые приходится учитывать:
+//   - в BRE группы, интервалы, "|", "+" и "?" пишутся с обратной косой чертой, а без неё - обычные символы (расширения GNU);
+//   - "*" в начале выражения или группы - обычный символ, "^" и "$" - якоря лишь по краям выражения (в BRE);
+//   - обратная косая черта внутри [...] - обычный символ, а в RE2 - экранирование;
+//   - границы слова "\<", "\>", "\b" и "\B" RE2 проверяет только для ASCII, поэтому они становятся пустыми группами-метками,
+//     а саму границу по буквам юникода проверяет checkedMatcher
+func translate(pattern string, basic bool) (string, error) {
+	var b strings.Builder
+	atStart := true // Начало выражения, группы или альтернативы: здесь "*" - обычный символ, а "^" - якорь
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(pattern) {
+				return "", errTrailingBackslash
+			}
+			n := pattern[i+1]
+			i += 2
+			switch {
+			case basic && n == '(':
+				b.WriteByte('(')
+				atStart = true
+				continue
+			case basic && n == '|':
+				b.WriteByte('|')
+				atStart = true
+				continue
+			case basic && (n == ')' || n == '+' || n == '?'):
+				b.WriteByte(n)
+			case basic && n == '{':
+				end := strings.Index(pattern[i:], `\}`)
+				if end < 0 {
+					return "", errUnmatchedInterval
+				}
+				b.WriteString("{" + pattern[i:i+end] + "}")
+				i += end + 2
+			case n == '<':
+				b.WriteString(`(?P<` + markWordStart + `>)`)
+			case n == '>':
+				b.WriteString(`(?P<` + markWordEnd + `>)`)
+			case n == 'b':
+				b.WriteString(`(?P<` + markWordBound + `>)`)
+			case n == 'B':
+				b.WriteString(`(?P<` + markNotWordBound + `>)`)
+			case n == '`':
+				b.WriteString(`\A`)
+			case n == '\'':
+				b.WriteString(`\z`)
+			case strings.IndexByte("wWsS", n) >= 0:
+				b.WriteString(`\` + string(n))
+			case n >= '1' && n <= '9':
+				return "", errBackReference
+			default: // Экранированный обычный символ: берём руну целиком, она может быть многобайтной
+				r, size := utf8.DecodeRuneInString(pattern[i-1:])
+				b.WriteString(regexp.QuoteMeta(string(r)))
+				i += size - 1
+			}
+		case c == '[':
+			class, size, err := translateBracket(pattern[i:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(class)
+			i += size
+		case c == '*' && atStart:
+			b.WriteString(`\*`)
+			i++
+		case c == '^':
+			if basic && !atStart { // В BRE "^" не в начале - обычный символ
+				b.WriteString(`\^`)
+			} else {
+				b.WriteByte('^')
+			}
+			i++
+			continue // После якоря "*" по-прежнему обычный символ
+		case c == '$':
+			rest := pattern[i+1:]
+			if basic && rest != "" && !strings.HasPrefix(rest, `\)`) && !strings.HasPrefix(rest, `\|`) { // В BRE "$" не в конце - обычный символ
+				b.WriteString(`\$`)
+			} else {
+				b.WriteByte('$')
+			}
+			i++
+		case basic && strings.IndexByte("(){}|+?", c) >= 0: // В BRE без обратной косой черты это обычные символы
+			b.WriteString(`\` + string(c))
+			i++
+		case !basic && (c == '(' || c == '|'):
+			b.WriteByte(c)
+			i++
+			atStart = true
+			continue
+		case !basic && (c == '+' || c == '?') && atStart:
+			b.WriteString(`\` + string(c))
+			i++
+		case !basic && c == '{':
+			if end, ok := intervalEnd(pattern[i:]); ok && !atStart {
+				b.WriteString(pattern[i : i+end])
+				i += end
+			} else { // Как и GNU grep, "{" без корректного интервала считаем обычным символом
+				b.WriteString(`\{`)
+				i++
+			}
+		case !basic && c == '}':
+			b.WriteString(`\}`)
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(pattern[i:])
+			b.WriteString(pattern[i : i+size])
+			i += size
+		}
+		atStart = false
+	}
+	return b.String(), nil
+}
+
+// intervalEnd проверяет, что s начинается с интервала "{m}", "{m,}", "{,n}" или "{m,n}", и возвращает его длину
+func intervalEnd(s string) (int, bool) {
+	end := strings.IndexByte(s, '}')
+	if end < 2 {
+		return 0, false
+	}
+	body := s[1:end]
+	if strings.Count(body, ",") > 1 || body == "," {
+		return 0, false
+	}
+	for _, r := range body {
+		if r != ',' && (r < '0' || r > '9') {
+			return 0, false
+		}
+	}
+	return end + 1, true
+}
+
+// translateBracket переводит скобочное выражение POSIX ("[...]" в начале s) в RE2 и возвращает его длину в s
+func translateBracket(s string) (string, int, error) {
+	var b strings.Builder
+	b.WriteByte('[')
+	i := 1
+	if i < len(s) && s[i] == '^' {
+		b.WriteByte('^')
+		i++
+	}
+	if i < len(s) && s[i] == ']' { // "]" сразу после "[" или "[^" - обычный символ
+		b.WriteString(`\]`)
+		i++
+	}
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ']':
+			b.WriteByte(']')
+			return b.String(), i + 1, nil
+		case c == '[' && i+1 < len(s) && (s[i+1] == ':' || s[i+1] == '=' || s[i+1] == '.'):
+			closing := string(s[i+1]) + "]"
+			end := strings.Index(s[i+2:], closing)
+			if end < 0 {
+				return "", 0, errUnmatchedBracket
+			}
+			name := s[i+2 : i+2+end]
+			if s[i+1] == ':' { // Именованный класс [:alpha:] RE2 понимает сам
+				b.WriteString("[:" + name + ":]")
+			} else { // [=a=] и [.a.] - без поддержки локалей это просто символ
+				b.WriteString(regexp.QuoteMeta(name))
+			}
+			i += 2 + end + 2
+		case c == '\\' || c == '[': // В POSIX это обычные символы, а в RE2 их нужно экранировать
+			b.WriteString(`\` + string(c))
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			b.WriteString(s[i : i+size])
+			i += size
+		}
+	}
+	return "", 0, errUnmatchedBracket
+}
@@ -0,0 +1,209 @@
+package grep
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		basic   bool
+		want    string
+		wantErr error
+	}{
+		{name: "BRE: + и ? - обычные символы", pattern: "a+b?", basic: true, want: `a\+b\?`},
+		{name: "BRE: экранированные операторы", pattern: `\(ab\)\+\|c\{2,3\}`, basic: true, want: `(ab)+|c{2,3}`},
+		{name: "BRE: * в начале", pattern: "*a*", basic: true, want: `\*a*`},
+		{name: "BRE: * в начале группы", pattern: `\(*a\)`, basic: true, want: `(\*a)`},
+		{name: "BRE: ^ и $ не по краям", pattern: "a^b$c$", basic: true, want: `a\^b\$c$`},
+		{name: "BRE: незакрытый интервал", pattern: `a\{2`, basic: true, wantErr: errUnmatchedInterval},
+		{name: "ERE: операторы", pattern: "(ab)+|c{2}", want: "(ab)+|c{2}"},
+		{name: "ERE: { без интервала", pattern: "a{1", want: `a\{1`},
+		{name: "ERE: экранированные символы", pattern: `a\+\.`, want: `a\+\.`},
+		{name: "границы слова", pattern: `\<кот\>\b\B`, want: `(?P<grepWordStart>)кот(?P<grepWordEnd>)(?P<grepWordBound>)(?P<grepNotWordBound>)`},
+		{name: "скобки: обратная косая черта", pattern: `[\a]`, want: `[\\a]`},
+		{name: "скобки: ] первым и класс", pattern: "[]а[:digit:]]", want: `[\]а[:digit:]]`},
+		{name: "незакрытая скобка", pattern: "[мышь", wantErr: errUnmatchedBracket},
+		{name: "обратная ссылка", pattern: `(a)\1`, wantErr: errBackReference},
+		{name: "косая черта в конце", pattern: `a\`, basic: true, wantErr: errTrailingBackslash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := translate(tt.pattern, tt.basic)
+			if err != tt.wantErr {
+				t.Fatalf("translate(%q) ошибка %v, ожидалась %v", tt.pattern, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("translate(%q) = %q, ожидалось %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		line  string
+		match bool
+		spans [][]int
+	}{
+		{
+			name:  "Ахо-Корасик: самое левое, затем самое длинное",
+			cfg:   Config{Patterns: []string{"шь", "мы", "мышь"}, Syntax: SyntaxFixed},
+			line:  "мышь, мышь",
+			match: true,
+			spans: [][]int{{0, 8}, {10, 18}},
+		},
+		{
+			name:  "Ахо-Корасик: шаблон внутри другого",
+			cfg:   Config{Patterns: []string{"abcd", "bc"}, Syntax: SyntaxFixed},
+			line:  "abce",
+			match: true,
+			spans: [][]int{{1, 3}},
+		},
+		{
+			name: "Ахо-Корасик: нет совпадений",
+			cfg:  Config{Patterns: []string{"he", "she", "hers"}, Syntax: SyntaxFixed},
+			line: "hi",
+		},
+		{
+			name:  "пустой шаблон совпадает с любой строкой",
+			cfg:   Config{Patterns: []string{"кот", ""}, Syntax: SyntaxFixed},
+			line:  "мышь",
+			match: true,
+		},
+		{
+			name: "пустой список шаблонов",
+			cfg:  Config{},
+			line: "мышь",
+		},
+		{
+			name:  "-w: слово целиком",
+			cfg:   Config{Patterns: []string{"мышь"}, WordRegexp: true},
+			line:  "мышьяк и мышь",
+			match: true,
+			spans: [][]int{{16, 24}},
+		},
+		{
+			name:  "-w: выбирается более короткий вариант совпадения",
+			cfg:   Config{Patterns: []string{"ab*"}, WordRegexp: true},
+			line:  "abbc ab",
+			match: true,
+			spans: [][]int{{5, 7}},
+		},
+		{
+			name:  "-w: соседние слова",
+			cfg:   Config{Patterns: []string{"и"}, Syntax: SyntaxFixed, WordRegexp: true},
+			line:  "и и",
+			match: true,
+			spans: [][]int{{0, 2}, {3, 5}},
+		},
+		{
+			name:  "-w: ^ не совпадает с середины строки",
+			cfg:   Config{Patterns: []string{"-a|^a"}, Syntax: SyntaxExtended, WordRegexp: true},
+			line:  "x-a",
+			match: false,
+		},
+		{
+			name:  "-w: ^ в начале строки",
+			cfg:   Config{Patterns: []string{"-a|^a"}, Syntax: SyntaxExtended, WordRegexp: true},
+			line:  "a-a",
+			match: true,
+			spans: [][]int{{0, 1}},
+		},
+		{
+			name:  "\\< и \\>: слова из кириллицы",
+			cfg:   Config{Patterns: []string{`\<мышь\>`}},
+			line:  "мышьяк дом мышь",
+			match: true,
+			spans: [][]int{{20, 28}},
+		},
+		{
+			name: "\\<: не начало слова",
+			cfg:  Config{Patterns: []string{`\<ышь`}},
+			line: "мышь",
+		},
+		{
+			name:  "\\b и \\B: кириллица",
+			cfg:   Config{Patterns: []string{`ы\Bш`, `\bдом\b`}, Syntax: SyntaxExtended},
+			line:  "мышь, дом",
+			match: true,
+			spans: [][]int{{2, 6}, {10, 16}},
+		},
+		{
+			name:  "граница слова в альтернативе, которая не совпала",
+			cfg:   Config{Patterns: []string{`ab\>|abc`}, Syntax: SyntaxExtended},
+			line:  "abcd",
+			match: true,
+			spans: [][]int{{0, 3}},
+		},
+		{
+			name:  "\\> и -w вместе",
+			cfg:   Config{Patterns: []string{`кот\>`}, WordRegexp: true},
+			line:  "котик кот",
+			match: true,
+			spans: [][]int{{11, 17}},
+		},
+		{
+			name: "-x: только вся строка",
+			cfg:  Config{Patterns: []string{"мышь", "кот"}, LineRegexp: true},
+			line: "кот и мышь",
+		},
+		{
+			name:  "POSIX: самое длинное совпадение",
+			cfg:   Config{Patterns: []string{"a|ab"}, Syntax: SyntaxExtended},
+			line:  "abc",
+			match: true,
+			spans: [][]int{{0, 2}},
+		},
+		{
+			name:  "Perl: первая подходящая альтернатива",
+			cfg:   Config{Patterns: []string{"a|ab"}, Syntax: SyntaxPerl},
+			line:  "abc",
+			match: true,
+			spans: [][]int{{0, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.cfg.newMatcher()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Match([]byte(tt.line)); got != tt.match {
+				t.Errorf("Match(%q) = %v, ожидалось %v", tt.line, got, tt.match)
+			}
+			if got := m.FindAll([]byte(tt.line)); !reflect.DeepEqual(got, tt.spans) {
+				t.Errorf("FindAll(%q) = %v, ожидалось %v", tt.line, got, tt.spans)
+			}
+		})
+	}
+}
+
+func TestPatternError(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "ERE", cfg: Config{Patterns: []string{"мышь", "a(b"}, Syntax: SyntaxExtended}},
+		{name: "BRE и -x", cfg: Config{Patterns: []string{`a\(b`}, LineRegexp: true}},
+		{name: "Perl и -i", cfg: Config{Patterns: []string{"a(b"}, Syntax: SyntaxPerl, IgnoreCase: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.newMatcher()
+			if !errors.Is(err, errBadPattern) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, errBadPattern)
+			}
+			pattern := tt.cfg.Patterns[len(tt.cfg.Patterns)-1]
+			if !strings.Contains(err.Error(), `"`+strings.ReplaceAll(pattern, `\`, `\\`)+`"`) || strings.Contains(err.Error(), "(?:") {
+				t.Errorf("в ошибке %q нет шаблона %q или есть внутренняя обёртка", err, pattern)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package grep
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Stdin - имя, под которым в списке файлов указывается стандартный ввод
+const Stdin = "-"
+
+var errIsDirectory = errors.New("это каталог")
+
+// WalkConfig - какие файлы просматривать, помимо перечисленных явно
+type WalkConfig struct {
+	Recursive      bool     // -r: обходить каталоги
+	FollowSymlinks bool     // -R: то же, но переходить и по символическим ссылкам внутри каталогов
+	Include        []string // --include: просматривать только файлы с подходящим именем
+	Exclude        []string // --exclude: пропускать файлы с подходящим именем
+	ExcludeDir     []string // --exclude-dir: не заходить в каталоги с подходящим именем
+	NoIgnore       bool     // --no-ignore: не учитывать .gitignore при обходе
+}
+
+// Walk перебирает файлы из paths (каталоги - рекурсивно, если задан Recursive) и вызывает fn для каждого.
+// Порядок детерминирован: аргументы - как указаны, содержимое каталогов - по алфавиту.
+// Ошибка доступа к отдельному пути передаётся в fn и не прерывает обход; обход прерывает только ошибка, которую вернула fn
+func Walk(paths []string, cfg WalkConfig, fn func(path string, err error) error) error {
+	w := &walker{cfg: cfg, fn: fn, visited: make(map[string]bool)}
+	if len(paths) == 0 {
+		if cfg.Recursive { // Как и GNU grep, с -r без аргументов просматриваем текущий каталог, печатая имена без "./"
+			return w.dir("", "", nil)
+		}
+		return fn(Stdin, nil)
+	}
+
+	for _, path := range paths {
+		if path == Stdin {
+			if err := fn(path, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := os.Stat(path) // Ссылки, указанные явно, раскрываются всегда
+		if err != nil {
+			if err := fn(path, err); err != nil {
+				return err
+			}
+			continue
+		}
+		switch {
+		case info.IsDir() && cfg.Recursive:
+			w.markVisited(path)
+			err = w.dir(path, "", nil)
+		case info.IsDir():
+			err = fn(path, &os.PathError{Op: "read", Path: path, Err: errIsDirectory})
+		case cfg.included(filepath.Base(path)):
+			err = fn(path, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type walker struct {
+	cfg     WalkConfig
+	fn      func(path string, err error) error
+	visited map[string]bool // Уже пройденные каталоги (по реальному пути) - защита от циклов из ссылок при -R
+}
+
+// dir обходит каталог path. rel - путь относительно корня обхода (для правил .gitignore),
+// rules - правила .gitignore из родительских каталогов
+func (w *walker) dir(path, rel string, rules ignoreRules) error {
+	readPath := path
+	if readPath == "" {
+		readPath = "."
+	}
+	if !w.cfg.NoIgnore {
+		rules = rules.load(readPath, rel)
+	}
+	entries, err := os.ReadDir(readPath) // ReadDir возвращает записи, уже отсортированные по имени
+	if err != nil {
+		return w.fn(readPath, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		child, childRel := joinPath(path, name), joinPath(rel, name)
+		isDir, regular := entry.IsDir(), entry.Type().IsRegular()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if !w.cfg.FollowSymlinks { // -r, в отличие от -R, пропускает ссылки внутри каталогов
+				continue
+			}
+			info, err := os.Stat(child)
+			if err != nil {
+				if err := w.fn(child, err); err != nil {
+					return err
+				}
+				continue
+			}
+			isDir, regular = info.IsDir(), info.Mode().IsRegular()
+		}
+
+		if isDir {
+			if matchAny(w.cfg.ExcludeDir, name) || w.ignored(name, childRel, true, rules) || !w.markVisited(child) {
+				continue
+			}
+			if err := w.dir(child, childRel, rules); err != nil {
+				return err
+			}
+			continue
+		}
+		if !regular || w.ignored(name, childRel, false, rules) || !w.cfg.included(name) { // Устройства, сокеты и каналы при обходе пропускаются
+			continue
+		}
+		if err := w.fn(child, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ignored сообщает, исключает ли путь .gitignore (каталог .git пропускается всегда, если .gitignore учитывается)
+func (w *walker) ignored(name, rel string, isDir bool, rules ignoreRules) bool {
+	if w.cfg.NoIgnore {
+		return false
+	}
+	return (isDir && name == ".git") || rules.ignored(rel, isDir)
+}
+
+// markVisited отмечает каталог пройденным и возвращает false, если он уже был пройден
+func (w *walker) markVisited(path string) bool {
+	if !w.cfg.FollowSymlinks { // Без -R по ссылкам не переходим, и зациклиться невозможно
+		return true
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		real = path
+	}
+	if w.visited[real] {
+		return false
+	}
+	w.visited[real] = true
+	return true
+}
+
+// included проверяет имя файла по --include/--exclude
+func (cfg WalkConfig) included(name string) bool {
+	if matchAny(cfg.Exclude, name) {
+		return false
+	}
+	return len(cfg.Include) == 0 || matchAny(cfg.Include, name)
+}
+
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath, в отличие от filepath.Join, не чистит путь: указанный пользователем "./" должен остаться в выводе
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return dir + name
+	}
+	return dir + string(filepath.Separator) + name
+}
@@ -0,0 +1,114 @@
+package grep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeTree создаёт во временном каталоге файлы с заданным содержимым
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestWalk(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		".gitignore":          "*.log\n/build/\n!keep.log\n",
+		"a.txt":               "",
+		"keep.log":            "",
+		"skip.log":            "",
+		"build/out.txt":       "",
+		"src/build/out.txt":   "",
+		"src/main.go":         "",
+		"src/.gitignore":      "gen*\n",
+		"src/gen.go":          "",
+		"vendor/lib/x.go":     "",
+		".git/HEAD":           "",
+		"docs/deep/guide.txt": "",
+	})
+
+	testCases := []struct {
+		name     string
+		cfg      WalkConfig
+		expected []string
+	}{
+		{
+			name: "с учётом .gitignore",
+			cfg:  WalkConfig{Recursive: true},
+			expected: []string{
+				".gitignore", "a.txt", "docs/deep/guide.txt", "keep.log",
+				"src/.gitignore", "src/build/out.txt", "src/main.go", "vendor/lib/x.go",
+			},
+		},
+		{
+			name: "без учёта .gitignore",
+			cfg:  WalkConfig{Recursive: true, NoIgnore: true},
+			expected: []string{
+				".git/HEAD", ".gitignore", "a.txt", "build/out.txt", "docs/deep/guide.txt", "keep.log", "skip.log",
+				"src/.gitignore", "src/build/out.txt", "src/gen.go", "src/main.go", "vendor/lib/x.go",
+			},
+		},
+		{
+			name:     "include и exclude-dir",
+			cfg:      WalkConfig{Recursive: true, Include: []string{"*.go", "*.txt"}, ExcludeDir: []string{"vendor", "docs"}},
+			expected: []string{"a.txt", "src/build/out.txt", "src/main.go"},
+		},
+		{
+			name:     "exclude",
+			cfg:      WalkConfig{Recursive: true, NoIgnore: true, Exclude: []string{".*", "*.log", "*.txt"}},
+			expected: []string{".git/HEAD", "src/gen.go", "src/main.go", "vendor/lib/x.go"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var actual []string
+			err := Walk([]string{root}, test.cfg, func(path string, err error) error {
+				if err != nil {
+					t.Fatal(err)
+				}
+				rel, _ := filepath.Rel(root, path)
+				actual = append(actual, filepath.ToSlash(rel))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("actual %v, expected %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestWalkDirectoryWithoutRecursive(t *testing.T) {
+	root := makeTree(t, map[string]string{"a.txt": ""})
+	var errs []error
+	err := Walk([]string{root, Stdin}, WalkConfig{}, func(path string, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+		} else if path != Stdin {
+			t.Errorf("неожиданный путь %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("ожидалась одна ошибка для каталога без -r, получено %v", errs)
+	}
+}
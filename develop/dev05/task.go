@@ -1,13 +1,12 @@
 package main
 
 import (
-	"flag"
+	"dev05/grep"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
-	"regexp"
-	"strings"
 )
 
 /*
@@ -27,267 +26,164 @@ import (
 
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
-var (
-	after int
-	before int
-	contextText int
-	countBool bool
-	ignoreCase bool
-	invert bool
-	fixed bool
-	lineNum bool
-	filePath string
-	help bool
-	isNotDefault bool // Если не будет вызвана ни одна функция-обработчик, isNotDefault так и останется "false" и будет вызвана ф-ция Default, просто возвращающая только строки с совпадением
-)
-func main() {
-	flag.IntVar(&after, "A", 0, "печатать N строк после совпадения")
-	flag.IntVar(&before, "B", 0, "печатать N строк до совпадения")
-	flag.IntVar(&contextText, "C", 0, "печатать ±N строк вокруг совпадения")
-	var count int
-	flag.BoolVar(&countBool, "c", false, "количество строк")
-	flag.BoolVar(&ignoreCase, "i", false, "игнорировать регистр")
-	flag.BoolVar(&invert, "v", false, "вместо совпадения, исключать")
-	flag.BoolVar(&fixed, "F", false, "точное совпадение со строкой, не паттерн")
-	flag.BoolVar(&lineNum, "n", false, "печатать номер строки")
-	flag.StringVar(&filePath,"fp", "", "указать абсолютный путь до файла")
-	flag.BoolVar(&help, "h", false, "показать помощь и выйти")
-	flag.Parse() // Парсим флаги и записываем их значения в нужные переменные
-	// В Golang у нас есть пакет под названием os, который содержит массив под названием «Args».
-	// Args - это массив строк, содержащий все переданные аргументы командной строки (разделителем между аргументами выступает символ пробела).
-	// Первым аргументом всегда будет имя программы. Последним мы условимся, что будет искомое строковое значение
-	phrase := os.Args[len(os.Args)-1] // str будет содержать искомое фразу
-
-	if help {
-		printHelpAndExit(0)
-	}
-
-	t, err := ioutil.ReadFile(filePath) // Читает файл, возвращает последовательность байтов и значение ошибки
-	if err != nil {
-		log.Fatal(err)
-	}
-	text := string(t) // Приводим слайс байтов к строковому значению
-
-	fmt.Println("===ИСХОДНЫЙ ТЕКСТ===")
-	fmt.Println(text) // text содержит исходный текст. В дальнейшем её значение будет модифицировано (присвоен результат работы программы)
-	fmt.Println("===РЕЗУЛЬТАТ===")
 
-	if lineNum {
-		fmt.Println("Find on", LineNum(phrase, text)+1, "line")
-	}
-
-	if countBool {
-		count = Count(text)
-		fmt.Println("Count of lines", count)
-	}
-
-	if ignoreCase {
-		text = IgnoreCase(phrase, text, after, before, contextText)
-	}
-
-	if after > 0 { // Если пользователь указал с помощью флага значение переменной after, отличное от нуля, значит он хочет чтобы было напечатано "after" строк после совпадения
-		text = After(phrase, text, after) // Вызываем ф-цю, возвращающую требуемый пользователем результат
-	}
-	// Аналогично вышеописанному
-	if before > 0 {
-		text = Before(phrase, text, before)
-	}
-	// Аналогично вышеописанному
-	if contextText > 0 {
-		text = ContextText(phrase, text, contextText)
-	}
+// Коды возврата, как у GNU grep
+const (
+	exitSelected = 0 // Выбрана хотя бы одна строка
+	exitNone     = 1 // Ни одной строки не выбрано
+	exitTrouble  = 2 // Ошибка (неверные опции, шаблон, недоступный файл), независимо от найденного
+)
 
-	if invert {
-		text = Invert(phrase, text)
-	}
+const usage = `Утилита grep. Использование: ./[название_исполняемого_файла] [опции] ШАБЛОН [ФАЙЛ...]
+   или: ./[название_исполняемого_файла] [опции] -e ШАБЛОН... [-f ФАЙЛ...] [ФАЙЛ...]
+Без файлов (или с файлом "-") читается стандартный ввод. Короткие флаги можно объединять: -inC2
+Доступные опции:
+  -A, --after-context=N   печатать N строк после совпадения
+  -B, --before-context=N  печатать N строк до совпадения
+  -C, --context=N         печатать ±N строк вокруг совпадения (явные -A/-B важнее)
+  -c, --count             печатать только количество выбранных строк
+  -i, --ignore-case       игнорировать регистр
+  -v, --invert-match      выбирать строки без совпадения
+  -G, --basic-regexp      шаблон - базовое регулярное выражение POSIX (по умолчанию)
+  -E, --extended-regexp   шаблон - расширенное регулярное выражение POSIX
+  -F, --fixed-strings     шаблон - обычная строка, а не регулярное выражение
+  -P, --perl-regexp       шаблон - регулярное выражение в стиле Perl (синтаксис RE2)
+  -e, --regexp=ШАБЛОН     искать ШАБЛОН; можно указать несколько раз
+  -f, --file=ФАЙЛ         брать шаблоны из ФАЙЛА, по одному на строку
+  -w, --word-regexp       совпадение должно быть целым словом
+  -x, --line-regexp       совпадение должно занимать всю строку
+  -n, --line-number       печатать номер строки
+  -r, --recursive         искать во всех файлах каталогов (без ФАЙЛОВ - в текущем каталоге)
+  -R, --dereference-recursive  то же, но переходить по символическим ссылкам
+      --include=ШАБЛОН    искать только в файлах с подходящим именем
+      --exclude=ШАБЛОН    пропускать файлы с подходящим именем
+      --exclude-dir=ШАБЛОН  пропускать каталоги с подходящим именем
+      --no-ignore         не учитывать .gitignore при обходе каталогов
+  -l, --files-with-matches   печатать только имена файлов с совпадениями
+  -L, --files-without-match  печатать только имена файлов без совпадений
+  -H, --with-filename     печатать имя файла перед каждой строкой
+  -h, --no-filename       не печатать имя файла
+  -o, --only-matching     печатать только совпавшие части строк
+  -m, --max-count=N       остановиться после N выбранных строк
+  -b, --byte-offset       печатать смещение в байтах от начала файла
+      --color[=КОГДА]     подсвечивать совпадения: auto (по умолчанию для --color), always или never
+      --json              печатать по JSON-объекту на каждую выбранную строку
+  -z, --decompress        распаковывать файлы, сжатые gzip, bzip2 или zstd (формат - по содержимому)
+      --archives          искать в файлах внутри архивов tar и zip, выводя их как АРХИВ:ФАЙЛ
+  -a, --text              считать двоичные файлы текстом
+  -I                      считать, что в двоичных файлах совпадений нет
+      --binary-files=ТИП  binary, text или without-match
+  -j N                    просматривать N файлов одновременно (по умолчанию - по числу процессоров)
+      --help              показать помощь и выйти
+`
+
+const stdinName = "(standard input)" // Так стандартный ввод называется в выводе
 
-	if fixed {
-		fmt.Println(Fixed(phrase, text))
-	}
-	// Лишь в конце проверяем, нужно ли выполнить действие по умолчанию (если пользователь не указал ни одного значения флага)
-	if !isNotDefault {
-		text = Default(phrase, text)
-	}
-	fmt.Println(text)
-}
-func printHelpAndExit(exitCode int) {
-	log.Printf(`Утилита grep. Использование: ./[название_исполняемого_файла] [опции] [искомый текст].
-Для того, чтобы вывести ТОЛЬКО строки, в которых встречается искомая фраза/значение, используйте любой из флагов -A, -B, -C со значением "0"
-Доступные опции:`)
-	flag.PrintDefaults()
-	os.Exit(exitCode)
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
-// Default просто возвращает строки, содержащие совпадение, без применения различных опций
-func Default(str, file string) (res string) {
-	expr, err := regexp.Compile(str) // Ф-ция Compile возвращает регулярное выражение
-	if err != nil {
-		fmt.Println(err)
-	}
 
-	rows := strings.Split(file, "\n")
-	for _, v := range rows {
-		if expr.MatchString(v) {
-			res += v
-		}
-	}
-	return
-}
-// After возвращает искомую строку и count строк ПОСЛЕ нее
-func After(str, file string, count int) (res string) { // вход: 1. Значение, строку в котором оно содержится, нужно найти. 2. Набор текстовых данных, в котором будем искать совпадение. 3. Кол-во строк после строки, где есть совпадение, для вывода
-	//expr := regexp.MustCompile(str)
-	expr, err := regexp.Compile(str) // Ф-ция Compile возвращает регулярное выражение
+// run выполняет grep с аргументами args и возвращает код возврата.
+// Ввод-вывод передаётся параметрами, чтобы программу целиком можно было прогнать в тестах
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintf(stderr, "grep: %v\n%s", err, usage)
+		return exitTrouble
 	}
-	rows := strings.Split(file, "\n") // Весь исходный набор текста разбиваем построчно на фрагменты и заполняем ими слайс строк
-	for i, v := range rows { // Анализируем каждую строчку исходного текста
-		if expr.MatchString(v) { // Метод MatchString проверяет, есть ли в строке вхождения регулярного выражения. Результат - true/false
-			res += v + "\n" // Используем оператор конкатенации для того, чтобы добавить строку исходного текста (с переводом строки) с совпадением в РЕЗУЛЬТИРУЮЩУЮ строковую переменную res
-			if !(i == len(rows)-1) { // Если строчка, в которой обнаружено совпадение - последняя, не добавляем n строчек после неё к результату, т. к. их больше нет
-				for j, k := range rows { // Значение v (одну из строчек исходного текста, в которой обнаружено совпадение) сравниваем с КАЖДОЙ строчкой этого же текста (сравниваются ИНДЕКСЫ (положение в массиве))
-					if j > i && j <= i+count { // Дальше отбираем строчки, находящиеся ПОСЛЕ строчки с совпадением, но в то же время не выходящие за границу (after, или лок. переменная count - то есть кол-во строк после совпадения)
-						//if j == len(rows)-1 || j == i+count { // Если строчка прошла предыдущий барьер, то: если она последняя в тексте или последняя в "разрешенном диапазоне" вывода
-						//	res += k // Добавляем ее к результату БЕЗ перевода строки (после неё уже точно никаких строк добавлено не будет)
-						//} else {
-						res += k + "\n" // Иначе добавляем с переводом строки
-						//}
-					}
-				}
-			}
-		}
+	if opts.help {
+		fmt.Fprint(stdout, usage)
+		return exitSelected
 	}
-	isNotDefault = true
-	return
-}
 
-// Before возвращает искомую строку и count строк ДО нее (ф-ция, симметричная After)
-func Before(str, file string, count int) (res string) {
-	expr, err := regexp.Compile(str) // Ф-ция Compile возвращает регулярное выражение
-	if err != nil {
-		fmt.Println(err)
-	}
-	// Аналогично функции After
-	rows := strings.Split(file, "\n")
-	for i, v := range rows {
-		if expr.MatchString(v) {
-			for j, k := range rows {
-				if j < i && j >= i-count {
-					res += k + "\n"
-				}
-			}
-			res += v // Здесь небольшое отличие от ф-ции After: строчку, в которой обнаружено совпадение, мы добавим к результату последней, чтобы было симметрично
+	if err := opts.loadPatterns(stdin); err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) { // Недоступный файл шаблонов (-f)
+			reportError(stderr, pathErr.Path, err)
+		} else {
+			fmt.Fprintln(stderr, "grep:", err)
 		}
+		return exitTrouble
 	}
-	isNotDefault = true
-	return
-}
-
-// ContextText возвращает искомую строку и count строк ВОКРУГ нее
-func ContextText(str, file string, count int) (res string) {
-	expr, err := regexp.Compile(str) // Ф-ция Compile возвращает регулярное выражение
-	if err != nil {
-		fmt.Println(err)
+	if opts.maxCount == 0 { // -m 0: не читать ничего, как и GNU grep
+		return exitNone
 	}
-	rows := strings.Split(file, "\n")
-	for i, v := range rows {
-		if expr.MatchString(v) {
-			for j, k := range rows { // Цикл добавляет к результату
-				if j < i && j >= i-count {
-					res += k + "\n"
-				}
+	cfg := opts.config()
+	cfg.Color = opts.color == "always" || (opts.color == "auto" && isTerminal(stdout))
+	searcher, err := grep.New(cfg) // Все флаги сведены в одну конфигурацию, шаблон компилируется один раз
+	if err != nil {
+		fmt.Fprintln(stderr, "grep:", err)
+		return exitTrouble
+	}
+
+	var (
+		selected bool // Выбрана ли хоть одна строка хоть в одном файле
+		trouble  bool // Была ли ошибка хоть с одним файлом
+	)
+	// Файлы просматриваются параллельно, но итоги приходят в порядке обхода - сообщения в stderr не перемешаются с выводом
+	err = searcher.SearchFiles(opts.files, opts.walk, opts.jobs, openSource(stdin), searcher.NewOutput(stdout),
+		func(path string, res grep.Result, err error) {
+			if err != nil { // Недоступный файл не прерывает поиск в остальных
+				reportError(stderr, path, err)
+				trouble = true
+				return
 			}
-			res += v + "\n"
-			for j, k := range rows {
-				if j > i && j <= i+count {
-					if j == len(rows)-1 || j == i+count {
-						res += k
-					} else {
-						res += k + "\n"
-					}
-				}
+			if res.Binary {
+				fmt.Fprintf(stderr, "grep: %s: binary file matches\n", displayName(path))
 			}
-		}
-	}
-	isNotDefault = true
-	return
-}
-
-// Count возвращает кол-во строк в исходном тексте
-func Count(file string) (count int) {
-	rows := strings.Split(file, "\n")
-	for range rows {
-		count++
+			selected = selected || res.Selected > 0
+		})
+	if err != nil {
+		fmt.Fprintln(stderr, "grep:", err)
+		return exitTrouble
 	}
-	isNotDefault = true
-	return
-}
 
-// IgnoreCase возвращает искомую строку игнорируя регистр
-func IgnoreCase(str string, file string, after int, before int, context int) (res string) {
-
-	str = strings.ToLower(str) // Возвращает строку, где все символы str приведены к нижнему регистру
-	file = strings.ToLower(file) // Сам текст, по которому будем искать, привести к нижнему регистру
-	// Здесь без if-а должно быть действие по умолчанию
-	if after > 0 {
-		res = After(str, file, after)
-		after = 0
-	}
-	if before > 0 {
-		res = Before(str, file, before)
-		before = 0
+	switch {
+	case trouble:
+		return exitTrouble
+	case selected:
+		return exitSelected
+	default:
+		return exitNone
 	}
-	// Аналогично вышеописанному
-	if context > 0 {
-		res = ContextText(str, file, contextText)
-		contextText = 0
-	}
-	return res
 }
 
-// Invert возвращает текст без искомой строки
-func Invert(str, file string) (res string) {
-	expr, err := regexp.Compile(str) // Ф-ция Compile возвращает регулярное выражение
-	if err != nil {
-		fmt.Println(err)
-	}
-	rows := strings.Split(file, "\n")
-	for i, v := range rows {
-		if expr.MatchString(v) {
-			continue
+// openSource возвращает grep.Opener, который вместо "-" подставляет стандартный ввод
+func openSource(stdin io.Reader) grep.Opener {
+	return func(path string) (string, io.ReadCloser, error) {
+		if path == grep.Stdin {
+			return stdinName, ioutil.NopCloser(stdin), nil
 		}
-		if i == len(rows)-1 {
-			res += v
-		} else {
-			res += v + "\n"
+		f, err := os.Open(path) // Файл не читается целиком, а передаётся движку как io.Reader
+		if err != nil {
+			return "", nil, err
 		}
+		return path, f, nil
 	}
-	isNotDefault = true
-	return
 }
 
-// Fixed возвращает true, если имеется точное совпадение, false в противном случае
-func Fixed(str, file string) bool {
-	isNotDefault = true
-	rows := strings.Split(file, "\n")
-	for _, v := range rows {
-		if v == str {
-			return true
-		}
+// isTerminal сообщает, что w - терминал, способный показать цвета
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
 	}
-	return false
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-// LineNum возвращает номер исходной строки
-func LineNum(str, file string) int {
-	expr, err := regexp.Compile(str) // Ф-ция Compile возвращает регулярное выражение
-	if err != nil {
-		fmt.Println(err)
+func displayName(path string) string {
+	if path == grep.Stdin {
+		return stdinName
 	}
-	rows := strings.Split(file, "\n")
-	for i, v := range rows {
-		if expr.MatchString(v) {
-			return i
-		}
+	return path
+}
+
+// reportError печатает ошибку в формате GNU grep: "grep: ФАЙЛ: причина"
+func reportError(stderr io.Writer, path string, err error) {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) { // Имя файла и так печатаем, из *os.PathError нужна только причина
+		err = pathErr.Err
 	}
-	return -1
-}
\ No newline at end of file
+	fmt.Fprintf(stderr, "grep: %s: %v\n", displayName(path), err)
+}
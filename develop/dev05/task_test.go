@@ -1,54 +1,94 @@
 package main
 
-import "testing"
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
 
-func TestAfter(t *testing.T) {
-	phrase := "солнце"
-	text := "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
-	count := 1
-	if After(phrase, text, count) != "дом солнце игра\nвысоко мышь луч\n" {
-		t.Error("результат не совпал с ожидаемым значением", After(phrase, text, count))
+// TestGNUConformance прогоняет программу целиком на сценариях из testdata/cases.txt
+// и сравнивает вывод и код возврата с записанными эталонами GNU grep
+func TestGNUConformance(t *testing.T) {
+	input, err := ioutil.ReadFile(filepath.Join("testdata", "input.txt"))
+	if err != nil {
+		t.Fatal(err)
 	}
-}
-
-func TestBefore(t *testing.T) {
-	phrase := "жук"
-	text := "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
-	count := 3
-	if Before(phrase, text, count) != "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра" {
-		t.Error("результат не совпал с ожидаемым значением:", Before(phrase, text, count))
+	cases, err := os.Open(filepath.Join("testdata", "cases.txt"))
+	if err != nil {
+		t.Fatal(err)
 	}
-}
+	defer cases.Close()
+
+	scanner := bufio.NewScanner(cases)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 4)
+		name, mode, args := parts[0], parts[2], strings.Fields(parts[3])
+		code, err := strconv.Atoi(parts[1])
+		if err != nil {
+			t.Fatalf("%s: неверный код возврата %q", name, parts[1])
+		}
 
-func TestContextText(t *testing.T) {
-	phrase := "солнце"
-	text := "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
-	count := 1
-	if ContextText(phrase, text, count) != "стол рука чашка\nдом солнце игра\nвысоко мышь луч" {
-		t.Error("результат не совпал с ожидаемым значением:", ContextText(phrase, text, count))
+		t.Run(name, func(t *testing.T) {
+			expected, err := ioutil.ReadFile(filepath.Join("testdata", "gnu", name+".out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stdout, stderr bytes.Buffer
+			actualCode := run(args, bytes.NewReader(input), &stdout, &stderr)
+			if actualCode != code {
+				t.Errorf("grep %v: код возврата %d, ожидалось %d (stderr: %s)", args, actualCode, code, stderr.String())
+			}
+			actual := stdout.String()
+			if mode == "sorted" { // Порядок обхода каталогов у GNU grep зависит от файловой системы
+				actual, expected = sortLines(actual), []byte(sortLines(string(expected)))
+			}
+			if actual != string(expected) {
+				t.Errorf("grep %v:\nполучено:\n%s\nожидалось:\n%s", args, actual, expected)
+			}
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
 	}
 }
 
-func TestCount(t *testing.T) {
-	text := "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
-	if Count(text) != 5 {
-		t.Error("результат не совпал с ожидаемым значением:", Count(text))
-	}
+func sortLines(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "")
 }
 
-func TestIgnoreCase(t *testing.T) {
-	phrase := "солНЦЕ"
-	text := "стол рука чашка\nдом сОлнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
-	after = 2
-	if IgnoreCase(phrase, text, after, before, contextText) != "дом солнце игра\nвысоко мышь луч\nцифра жук зебра\n" {
-		t.Error("результат не совпал с ожидаемым значением:", IgnoreCase(phrase, text, after, before, contextText))
+func TestRunTrouble(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "неверное регулярное выражение", args: []string{"[мышь"}},
+		{name: "неизвестная опция", args: []string{"-Q", "мышь"}},
+		{name: "неверная длина контекста", args: []string{"-A", "два", "мышь"}},
+		{name: "нет шаблона", args: []string{"-n"}},
+		{name: "файл не существует", args: []string{"мышь", filepath.Join("testdata", "нет-такого")}},
 	}
-}
 
-func TestInvert(t *testing.T) {
-	phrase := "мышь"
-	text := "стол рука чашка\nдом солнце игра\nвысоко мышь луч\nцифра жук зебра\nмышь его говори"
-	if Invert(phrase, text) != "стол рука чашка\nдом солнце игра\nцифра жук зебра\n" {
-		t.Error("результат не совпал с ожидаемым значением:", Invert(phrase, text))
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(test.args, strings.NewReader("мышь\n"), &stdout, &stderr); code != exitTrouble {
+				t.Errorf("код возврата %d, ожидалось %d", code, exitTrouble)
+			}
+			if stderr.Len() == 0 {
+				t.Error("сообщение об ошибке не выведено")
+			}
+		})
 	}
-}
\ No newline at end of file
+}
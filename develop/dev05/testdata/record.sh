@@ -0,0 +1,13 @@
+#!/bin/sh
+# Перезаписывает эталонный вывод в gnu/ настоящим GNU grep.
+# Запускается из любого каталога: testdata/record.sh. Пути в аргументах - относительно dev05, как и в тестах
+cd "$(dirname "$0")/.." || exit 2
+export LC_ALL=C.UTF-8
+grep -v '^#' testdata/cases.txt | while IFS="$(printf '\t')" read -r name code _ args; do
+	# shellcheck disable=SC2086 # аргументы намеренно разбиваются по пробелам
+	grep $args < testdata/input.txt > "testdata/gnu/$name.out" 2>/dev/null
+	status=$?
+	if [ "$status" != "$code" ]; then
+		echo "$name: GNU grep вернул $status, в cases.txt указано $code" >&2
+	fi
+done
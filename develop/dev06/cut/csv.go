@@ -0,0 +1,182 @@
+package cut
+
+import (
+	"dev06/managers"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errUnclosedQuote = errors.New("Кавычка поля не закрыта до конца файла")
+	errUnknownColumn = errors.New("Такого столбца нет в заголовке")
+)
+
+// nextRecord читает очередную запись CSV и возвращает её выбранные поля. Первая запись каждого файла - заголовок:
+// по нему имена столбцов из списка полей превращаются в номера, а выводится он, как и остальные записи
+func (m *ManCut) nextRecord() (string, bool, error) {
+	record, err := m.readRecord()
+	if err != nil {
+		return "", false, err
+	}
+	if file := m.manager.FileNumber(); file != m.headerFile {
+		m.headerFile = file
+		if err := m.resolveColumns(record); err != nil {
+			return "", false, err
+		}
+	}
+	return m.cutRecord(record)
+}
+
+// readRecord читает запись CSV по RFC 4180: поле в кавычках может содержать разделитель, удвоенные кавычки ("")
+// и переводы строк, тогда запись занимает несколько строк. "\r\n" в конце строки читается как перевод строки.
+// Как и в большинстве программ, читающих CSV, кавычка внутри поля без кавычек и символы после закрывающей кавычки
+// берутся как есть, а не считаются ошибкой
+func (m *ManCut) readRecord() ([]string, error) {
+	line, err := m.manager.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	delim, _ := utf8.DecodeRuneInString(m.options.fieldDelimeter())
+	var record []string
+	var field strings.Builder
+	quoted := false    // Сейчас внутри кавычек
+	fieldStart := true // Ещё не было ни одного символа поля: только здесь кавычка открывает поле в кавычках
+	for {
+		line = strings.TrimSuffix(line, "\r")
+		for i := 0; i < len(line); {
+			r, size := utf8.DecodeRuneInString(line[i:])
+			raw := line[i : i+size] // Пишем исходные байты, а не r: неверный UTF-8 не должен превращаться в U+FFFD
+			i += size
+			switch {
+			case quoted && r == '"':
+				if strings.HasPrefix(line[i:], `"`) { // Удвоенная кавычка - это кавычка внутри поля
+					field.WriteByte('"')
+					i++
+				} else {
+					quoted = false
+				}
+			case quoted:
+				field.WriteString(raw)
+			case r == '"' && fieldStart:
+				quoted = true
+				fieldStart = false
+			case r == delim:
+				record = append(record, field.String())
+				field.Reset()
+				fieldStart = true
+			default:
+				field.WriteString(raw)
+				fieldStart = false
+			}
+		}
+		if !quoted {
+			return append(record, field.String()), nil
+		}
+		if m.manager.EndOfFile() { // Следующая строка - уже из другого файла, её не склеиваем с этой записью
+			return nil, &managers.FileError{Name: m.manager.Name(), Err: errUnclosedQuote}
+		}
+		field.WriteByte('\n')
+		if line, err = m.manager.ReadLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// resolveColumns находит в заголовке header номера столбцов, выбранных по имени
+func (m *ManCut) resolveColumns(header []string) error {
+	m.columns = make([]int, len(m.options.selectors))
+	for i, s := range m.options.selectors {
+		if s.name == "" {
+			continue
+		}
+		m.columns[i] = -1
+		for j, name := range header {
+			if name == s.name {
+				m.columns[i] = j
+				break
+			}
+		}
+		if m.columns[i] < 0 { // Опечатка в имени - ошибка для всего запуска, а не повод молча вывести пустые поля
+			return fmt.Errorf("%w: %q", errUnknownColumn, s.name)
+		}
+	}
+	return nil
+}
+
+// cutRecord возвращает выбранные поля записи, снова записанные в CSV.
+// Запись из одного поля, как и строка без разделителя в обычном режиме, выводится целиком, если только не указан -s
+func (m *ManCut) cutRecord(record []string) (string, bool, error) {
+	if len(record) < 2 {
+		if m.options.separated {
+			return "", false, nil
+		}
+		return m.joinRecord(record), true, nil
+	}
+	return m.joinRecord(m.selectRecord(record)), true, nil
+}
+
+// selectRecord выбирает поля записи в порядке списка, с повторами: "3,1,1" -> третье, первое и снова первое.
+// С complement выбираются поля, не попавшие в список, в порядке записи
+func (m *ManCut) selectRecord(record []string) []string {
+	var res []string
+	if !m.options.complement {
+		for i := range m.options.selectors {
+			lo, hi := m.bounds(i, len(record))
+			res = append(res, record[lo:hi]...)
+		}
+		return res
+	}
+	chosen := make([]bool, len(record))
+	for i := range m.options.selectors {
+		lo, hi := m.bounds(i, len(record))
+		for j := lo; j < hi; j++ {
+			chosen[j] = true
+		}
+	}
+	for j, field := range record {
+		if !chosen[j] {
+			res = append(res, field)
+		}
+	}
+	return res
+}
+
+// bounds возвращает полуинтервал [lo, hi) номеров полей записи из n полей, выбранных i-м элементом списка.
+// Поля, которых в записи нет, пропускаются
+func (m *ManCut) bounds(i, n int) (int, int) {
+	s := m.options.selectors[i]
+	if s.name != "" {
+		if j := m.columns[i]; j < n {
+			return j, j + 1
+		}
+		return 0, 0
+	}
+	lo, hi := s.lo-1, s.hi
+	if hi > n {
+		hi = n
+	}
+	if lo >= hi {
+		return 0, 0
+	}
+	return lo, hi
+}
+
+// joinRecord соединяет поля выходным разделителем, заключая в кавычки те, что без кавычек прочитать обратно нельзя
+func (m *ManCut) joinRecord(fields []string) string {
+	delim := m.options.joiner()
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = quoteField(field, delim)
+	}
+	return strings.Join(quoted, delim)
+}
+
+// quoteField заключает поле в кавычки, удваивая кавычки внутри, если в нём есть разделитель, кавычка или перевод строки
+func quoteField(field, delim string) string {
+	if (delim == "" || !strings.Contains(field, delim)) && !strings.ContainsAny(field, "\"\r\n") {
+		return field
+	}
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
@@ -3,23 +3,22 @@ package cut
 import (
 	"dev06/managers"
 	"errors"
+	"io"
 	"log"
 	"strings"
 )
 
 var (
-	errDataNotProvided      = errors.New("Вы ничего не написали (ни одного столбца)")
-	errInvalidFieldPosition = errors.New("Какие-то (или все) номера полей, указанные для вывода, не существуют")
+	errFiles = errors.New("Не все входные файлы удалось прочитать")
 )
 
 // ManCut ...
 type ManCut struct {
 	manager *managers.ConsoleManager
-	options Options  // Поле options содержит меняющиеся параметры (те, что могут устанавливаться с помощью флагов), используем композицию для добавления необходимых полей
-	data    []string // входные данные
-	result  []string // результат работы программы
-	//delimeter              string // значение разделителя
-	//onlySeparated          bool // только строки с разделителем
+	options Options // Поле options содержит меняющиеся параметры (те, что могут устанавливаться с помощью флагов), используем композицию для добавления необходимых полей
+	// Для --csv: номер файла, чей заголовок уже прочитан, и номера столбцов для выбранных по имени полей (по порядку списка)
+	headerFile int
+	columns    []int
 }
 
 // New ...
@@ -44,45 +43,87 @@ func (m *ManCut) ApplyOptions(options ...Option) *ManCut { // ApplyOptions пр
 	return m
 }
 
-// Cut разбивает по разделителю
-func (m *ManCut) Cut() error {
-	data, err := m.manager.Read() // Метод Read значения ConsoleManager читает пользовательский ввод построчно, добавляет каждую строку в слайс
-	if err != nil {
+// Cut читает строки до конца всех входных файлов и сразу выводит выбранную часть каждой.
+// Файл, который не удалось прочитать, пропускается с сообщением в лог, а Cut в конце вернёт errFiles
+func (m *ManCut) Cut() (err error) {
+	if err := m.options.validate(); err != nil {
 		return err
 	}
-	if len(data) == 0 {
-		return errDataNotProvided // Если метод Read вернул нам пустой слайс строк, вернём ошибку
+	defer func() { // Закрываем файлы и выводим остаток результата, даже если прервались на ошибке
+		if closeErr := m.manager.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	failed := false
+	for {
+		res, ok, err := m.next() // Строки читаем по одной, поэтому размер входных данных не ограничен памятью
+		if err == io.EOF {
+			break
+		}
+		var fileErr *managers.FileError
+		if errors.As(err, &fileErr) { // Как и GNU cut, сообщаем об ошибке и продолжаем со следующего файла
+			log.Print(err)
+			failed = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := m.manager.WriteLine(res); err != nil {
+				return err
+			}
+		}
+	}
+	if failed {
+		return errFiles
 	}
-	m.data = data // Далее присваиваем прочитанные данные полю data структуры ManCut
-
-	SelectFields(m)
 	return nil
 }
 
-func (m *ManCut) OutputResult() error {
-	if err := m.manager.Write(m.result); err != nil {
-		return err
+// next читает очередную строку (в режиме --csv - запись) и возвращает её выбранную часть. false - выводить нечего
+func (m *ManCut) next() (string, bool, error) {
+	if m.options.csv {
+		return m.nextRecord()
 	}
-	return nil
+	line, err := m.manager.ReadLine()
+	if err != nil {
+		return "", false, err
+	}
+	res, ok := m.cutLine(line)
+	return res, ok, nil
 }
-func SelectFields(m *ManCut) {
-	for _, line := range m.data { // line здесь - это строка пользовательского ввода
-		if !strings.Contains(line, m.options.delimeter) { // Если в этой строке нет разделителя...
-			if !m.options.separated { // ...и не указано, что выводим строки только с разделителем...
-				m.result = append(m.result, line) // ...то добавляем строку к результату
+
+// cutLine возвращает выбранную часть строки. false - строку выводить не нужно (в ней нет разделителя и указан -s)
+func (m *ManCut) cutLine(line string) (string, bool) {
+	o := &m.options
+	var b strings.Builder
+	switch o.mode {
+	case modeBytes:
+		for i, seg := range o.segments(len(line)) {
+			if i > 0 {
+				b.WriteString(o.joiner())
 			}
-			continue
+			b.WriteString(line[seg[0]:seg[1]])
 		}
-		samples := strings.Split(line, m.options.delimeter) // Если же в строке разделитель есть, то делим её по нему на подстроки (это будут столбцы)
-
-		var prepared []string
-		for _, fieldID := range m.options.fields { // Перебираем номера столбцов, которые нужно вывести...
-			if fieldID > len(samples)-1 { // Если какие-то из номеров полей, указанные пользователем, не существуют, завершаемся с ошибкой
-				log.Fatal(errInvalidFieldPosition)
+	case modeChars:
+		runes := []rune(line) // Считаем символы, а не байты: "ж" в UTF-8 занимает два байта
+		for i, seg := range o.segments(len(runes)) {
+			if i > 0 {
+				b.WriteString(o.joiner())
 			}
-			prepared = append(prepared, samples[fieldID]) // ...и заполняем новый слайс элементами с нужным индексом из слайса samples
+			b.WriteString(string(runes[seg[0]:seg[1]]))
+		}
+	case modeFields:
+		samples, ok := o.splitFields(line) // Если в строке разделитель есть, то делим её по нему на подстроки (это будут столбцы)
+		if !ok {                           // Если же в этой строке нет разделителя, выводим её целиком, если только не указан -s
+			return line, !o.separated
+		}
+		var prepared []string
+		for _, seg := range o.segments(len(samples)) { // Поля, которых в строке нет, просто не попадают ни в один отрезок
+			prepared = append(prepared, samples[seg[0]:seg[1]]...)
 		}
-		preparedString := strings.Join(prepared, m.options.delimeter)
-		m.result = append(m.result, preparedString)
+		return strings.Join(prepared, o.joiner()), true
 	}
+	return b.String(), true
 }
@@ -1,26 +1,49 @@
 package cut
 
 import (
+	"bytes"
+	"dev06/managers"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
-func TestSetFields(t *testing.T) {
-	mc := &ManCut{options: GetDefaultOptions()}
+// cutLines пропускает строки data через Cut с настройками options и возвращает выведенные строки
+func cutLines(t *testing.T, data []string, options ...Option) []string {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, line := range data {
+		in.WriteString(line + "\n")
+	}
+	mc := New(managers.NewConsoleManager(&in, &out)).ApplyOptions(options...)
+	if err := mc.Cut(); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
 
+func TestSetFields(t *testing.T) {
 	testCases := []struct {
-		name          string
-		delimeter     string
-		separated     bool
-		fields        []int
-		data          []string
-		expected      []string
+		name      string
+		delimeter string
+		separated bool
+		fields    string
+		data      []string
+		expected  []string
 	}{
 		{
-			name:          "в качестве разделителя пробел",
-			delimeter:     " ",
+			name:      "в качестве разделителя пробел",
+			delimeter: " ",
 			separated: false,
-			fields:        []int{0, 1, 2},
+			fields:    "1,2,3",
 			data: []string{
 				`стол рука чашка`,
 				`дом солнце игра`,
@@ -31,10 +54,10 @@ func TestSetFields(t *testing.T) {
 			},
 		},
 		{
-			name:          "в качестве разделителя звездочка, только первые 2 поля",
-			delimeter:     "*",
+			name:      "в качестве разделителя звездочка, только первые 2 поля",
+			delimeter: "*",
 			separated: false,
-			fields:        []int{0, 1},
+			fields:    "1,2",
 			data: []string{
 				`стол*рука*чашка`,
 				`дом*солнце*игра`,
@@ -45,10 +68,10 @@ func TestSetFields(t *testing.T) {
 			},
 		},
 		{
-			name:          "только строки с разделителем, только первые 2 поля",
-			delimeter:     "#",
+			name:      "только строки с разделителем, только первые 2 поля",
+			delimeter: "#",
 			separated: true,
-			fields:        []int{0, 1},
+			fields:    "1,2",
 			data: []string{
 				`стол#рука#чашка`,
 				`дом*солнце*игра`,
@@ -57,22 +80,371 @@ func TestSetFields(t *testing.T) {
 				`стол#рука`,
 			},
 		},
+		{
+			name:      "отрезки, порядок и повторы в списке не важны",
+			delimeter: ":",
+			fields:    "4-,-2,1",
+			data: []string{
+				`а:б:в:г:д`,
+				`а:б`,
+			},
+			expected: []string{
+				`а:б:г:д`,
+				`а:б`,
+			},
+		},
+		{
+			name:      "пустые строки не прерывают чтение",
+			delimeter: ":",
+			fields:    "2",
+			data: []string{
+				`а:б`,
+				``,
+				`в:г`,
+				``,
+			},
+			expected: []string{
+				`б`,
+				``,
+				`г`,
+				``,
+			},
+		},
+		{
+			name:      "несуществующие поля пропускаются",
+			delimeter: ":",
+			fields:    "2,5",
+			data: []string{
+				`а:б:в`,
+				`а`,
+			},
+			expected: []string{
+				`б`,
+				`а`,
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := cutLines(t, test.data,
+				SetFieldsOption(test.fields),
+				SetDelimeterOption(test.delimeter),
+				SetSeparatedOption(test.separated),
+			)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("actual %v, expected %v", actual, test.expected)
+			}
+		})
+	}
+
+}
+
+func TestSelectBytesAndCharacters(t *testing.T) {
+	data := []string{`привет, мир`, `abcdef`}
+	testCases := []struct {
+		name     string
+		options  []Option
+		expected []string
+	}{
+		{
+			name:     "символы кириллицы не разрезаются",
+			options:  []Option{SetCharactersOption("1-3,9-")},
+			expected: []string{`примир`, `abc`},
+		},
+		{
+			name:     "байты",
+			options:  []Option{SetBytesOption("1-4,13-")},
+			expected: []string{`пр, мир`, `abcd`},
+		},
+		{
+			name:     "разделитель между отрезками",
+			options:  []Option{SetCharactersOption("-2,3-4,6"), SetOutputDelimeterOption("|")},
+			expected: []string{`пр|ив|т`, `ab|cd|f`},
+		},
+		{
+			name:     "всё, кроме выбранного",
+			options:  []Option{SetCharactersOption("2-7"), SetComplementOption(true), SetOutputDelimeterOption("...")},
+			expected: []string{`п... мир`, `a`},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := cutLines(t, data, test.options...)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("actual %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestComplementFields(t *testing.T) {
+	actual := cutLines(t, []string{"1\t2\t3\t4\t5\t6", "1\t2"},
+		SetFieldsOption("2,4-5"), SetComplementOption(true), SetOutputDelimeterOption(", "))
+
+	expected := []string{"1, 3, 6", "1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %q, expected %q", actual, expected)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	testCases := []struct {
+		list     string
+		expected []fieldRange
+		err      error
+	}{
+		{list: "3", expected: []fieldRange{{3, 3}}},
+		{list: "5-,1-2,2-3", expected: []fieldRange{{1, 3}, {5, math.MaxInt}}},
+		{list: "-4,3-4,4", expected: []fieldRange{{1, 4}}},
+		{list: "1-2,3-4", expected: []fieldRange{{1, 2}, {3, 4}}},
+		{list: "", err: errEmptyFields},
+		{list: "-", err: errOpenRange},
+		{list: "0", err: errNegativeFieldValue},
+		{list: "1,,2", err: errUnparsedField},
+		{list: "a-b", err: errUnparsedField},
+		{list: "3-2", err: errDecreasingRange},
+	}
+	for _, test := range testCases {
+		actual, err := parseList(test.list)
+		if err != test.err || !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%q: actual %v, %v, expected %v, %v", test.list, actual, err, test.expected, test.err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := New(nil).ApplyOptions().options.validate(); err != errNoList {
+		t.Errorf("actual %v, expected %v", err, errNoList)
+	}
+	o := New(nil).ApplyOptions(SetBytesOption("1"), SetSeparatedOption(true)).options
+	if err := o.validate(); err != errSeparatedNotFields {
+		t.Errorf("actual %v, expected %v", err, errSeparatedNotFields)
+	}
+	o = GetDefaultOptions()
+	if err := SetFieldsOption("1")(&o); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBytesOption("1")(&o); err != errManyLists {
+		t.Errorf("actual %v, expected %v", err, errManyLists)
+	}
+}
+
+func TestCutFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := ioutil.WriteFile(first, []byte("а:б\nв:г"), 0o644); err != nil { // Последняя строка без перевода строки
+		t.Fatal(err)
 	}
+	long := strings.Repeat("ж", 100000) // Длиннее буфера bufio.Scanner
+	if err := ioutil.WriteFile(second, []byte("д:е\n"+long+":з\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	names := []string{first, filepath.Join(dir, "missing.txt"), "-", dir, second}
+	manager := managers.NewFilesManager(names, strings.NewReader("stdin:ввод\n"), &out)
+	err := New(manager).ApplyOptions(SetFieldsOption("1"), SetDelimeterOption(":")).Cut()
+
+	if err != errFiles {
+		t.Errorf("actual error %v, expected %v", err, errFiles)
+	}
+	expected := "а\nв\nstdin\nд\n" + long + "\n"
+	if out.String() != expected {
+		t.Errorf("actual %.40q, expected %.40q", out.String(), expected)
+	}
+}
 
+func TestFileError(t *testing.T) {
+	var out bytes.Buffer
+	manager := managers.NewFilesManager([]string{filepath.Join(t.TempDir(), "missing.txt")}, nil, &out)
+	_, err := manager.ReadLine()
+	var fileErr *managers.FileError
+	if !errors.As(err, &fileErr) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("actual %v, expected *managers.FileError for missing file", err)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	data := []string{
+		`имя,город,заметка`,
+		`"Иванов, И.",Москва,"сказал ""да""`,
+		`и ушёл"`,
+		`Петров,Тверь,`,
+		`одно поле`,
+	}
+	testCases := []struct {
+		name     string
+		options  []Option
+		expected []string
+	}{
+		{
+			name:    "по именам и номерам, с перестановкой и повтором",
+			options: []Option{SetFieldsOption("заметка,имя,1")},
+			expected: []string{
+				`заметка,имя,имя`,
+				`"сказал ""да""`,
+				`и ушёл","Иванов, И.","Иванов, И."`,
+				`,Петров,Петров`,
+				`одно поле`,
+			},
+		},
+		{
+			name:    "кавычки снимаются, когда они больше не нужны",
+			options: []Option{SetFieldsOption("1-2"), SetOutputDelimeterOption(";"), SetSeparatedOption(true)},
+			expected: []string{
+				`имя;город`,
+				`Иванов, И.;Москва`,
+				`Петров;Тверь`,
+			},
+		},
+		{
+			name:    "всё, кроме выбранного",
+			options: []Option{SetFieldsOption("город"), SetComplementOption(true), SetSeparatedOption(true)},
+			expected: []string{
+				`имя,заметка`,
+				`"Иванов, И.","сказал ""да""`,
+				`и ушёл"`,
+				`Петров,`,
+			},
+		},
+	}
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
-			mc.result = []string{}
-			mc.options.delimeter = test.delimeter
-			mc.options.separated = test.separated
-			mc.options.fields = test.fields
-			mc.data = test.data
+			actual := cutLines(t, data, append([]Option{SetCSVOption(true)}, test.options...)...)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("actual %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestCSVFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.tsv")
+	second := filepath.Join(dir, "second.tsv")
+	if err := ioutil.WriteFile(first, []byte("a\tb\r\n1\t\"2\r\n"), 0o644); err != nil { // Кавычка не закрыта до конца файла
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(second, []byte("b\ta\r\n3\t4\r\n"), 0o644); err != nil { // У каждого файла свой заголовок
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	manager := managers.NewFilesManager([]string{first, second}, nil, &out)
+	err := New(manager).ApplyOptions(SetCSVOption(true), SetDelimeterOption("\t"), SetFieldsOption("b")).Cut()
 
-			SelectFields(mc)
+	if err != errFiles {
+		t.Errorf("actual error %v, expected %v", err, errFiles)
+	}
+	expected := "b\nb\n3\n"
+	if out.String() != expected {
+		t.Errorf("actual %q, expected %q", out.String(), expected)
+	}
+}
 
-			if !reflect.DeepEqual(mc.result, test.expected) {
-				t.Errorf("actual %v, expected %v", mc.result, test.expected)
+func TestCSVValidate(t *testing.T) {
+	testCases := []struct {
+		options []Option
+		err     error
+	}{
+		{options: []Option{SetFieldsOption("имя")}, err: errNamesWithoutCSV},
+		{options: []Option{SetCSVOption(true), SetCharactersOption("1")}, err: errCSVNotFields},
+		{options: []Option{SetCSVOption(true), SetFieldsOption("1"), SetDelimeterOption("::")}, err: errCSVDelimeter},
+		{options: []Option{SetCSVOption(true), SetFieldsOption("1"), SetDelimeterOption(`"`)}, err: errCSVDelimeter},
+		{options: []Option{SetCSVOption(true), SetFieldsOption("имя,1-")}},
+	}
+	for _, test := range testCases {
+		o := New(nil).ApplyOptions(test.options...).options
+		if err := o.validate(); err != test.err {
+			t.Errorf("actual %v, expected %v", err, test.err)
+		}
+	}
+
+	var out bytes.Buffer
+	manager := managers.NewConsoleManager(strings.NewReader("имя,город\n"), &out)
+	err := New(manager).ApplyOptions(SetCSVOption(true), SetFieldsOption("фамилия")).Cut()
+	if !errors.Is(err, errUnknownColumn) {
+		t.Errorf("actual %v, expected %v", err, errUnknownColumn)
+	}
+}
+
+func TestRegexAndWhitespaceDelimeters(t *testing.T) {
+	data := []string{
+		`  PID TTY      TIME   CMD`,
+		`12345 pts/0    00:00  bash`,
+		`без-разделителей`,
+	}
+	testCases := []struct {
+		name     string
+		options  []Option
+		data     []string // Своя входная строка вместо общей data
+		expected []string
+	}{
+		{
+			name:     "пробелы как в awk",
+			options:  []Option{SetWhitespaceOption(true), SetFieldsOption("4,1")},
+			expected: []string{`PID CMD`, `12345 bash`, `без-разделителей`},
+		},
+		{
+			name:     "пробелы, выходной разделитель и -s",
+			options:  []Option{SetWhitespaceOption(true), SetFieldsOption("2-3"), SetOutputDelimeterOption("\t"), SetSeparatedOption(true)},
+			expected: []string{"TTY\tTIME", "pts/0\t00:00"},
+		},
+		{
+			name:     "пробелы: другие пробельные символы - часть поля",
+			options:  []Option{SetWhitespaceOption(true), SetFieldsOption("1")},
+			data:     []string{"10\u00a0000\tруб.", "a\vb c"},
+			expected: []string{"10\u00a0000", "a\vb"},
+		},
+		{
+			name:     "регулярное выражение",
+			options:  []Option{SetRegexDelimeterOption(`[ -]{2,}|-`), SetFieldsOption("1,3"), SetOutputDelimeterOption("|")},
+			expected: []string{`|TIME`, `12345 pts/0|bash`, `без`},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			input := data
+			if test.data != nil {
+				input = test.data
+			}
+			actual := cutLines(t, input, test.options...)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("actual %q, expected %q", actual, test.expected)
 			}
 		})
 	}
+}
 
-}
\ No newline at end of file
+func TestDelimeterOptionsValidate(t *testing.T) {
+	o := GetDefaultOptions()
+	if err := SetRegexDelimeterOption("(")(&o); !errors.Is(err, errRegexDelimeter) {
+		t.Errorf("actual %v, expected %v", err, errRegexDelimeter)
+	}
+	if err := SetRegexDelimeterOption(" *")(&o); err != errEmptyMatch {
+		t.Errorf("actual %v, expected %v", err, errEmptyMatch)
+	}
+
+	testCases := []struct {
+		options []Option
+		err     error
+	}{
+		{options: []Option{SetFieldsOption("1"), SetWhitespaceOption(true), SetDelimeterOption(",")}, err: errManyDelimeters},
+		{options: []Option{SetFieldsOption("1"), SetWhitespaceOption(true), SetRegexDelimeterOption(",")}, err: errManyDelimeters},
+		{options: []Option{SetBytesOption("1"), SetWhitespaceOption(true)}, err: errDelimeterNotFields},
+		{options: []Option{SetCSVOption(true), SetFieldsOption("1"), SetRegexDelimeterOption(",")}, err: errCSVDelimeter},
+		{options: []Option{SetFieldsOption("1"), SetRegexDelimeterOption(",")}},
+	}
+	for _, test := range testCases {
+		o := New(nil).ApplyOptions(test.options...).options
+		if err := o.validate(); err != test.err {
+			t.Errorf("actual %v, expected %v", err, test.err)
+		}
+	}
+}
@@ -2,26 +2,75 @@ package cut
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 var (
-	errUnparsedField      = errors.New("Не удалось распарсить указанные поля, используйте ТОЛЬКО целые числа, разделенные запятыми")
-	errNegativeFieldValue = errors.New("Значение, представляющее номер столбца, должно быть НЕОТРИЦАТЕЛЬНЫМ")
+	errUnparsedField      = errors.New("Не удалось распарсить список, используйте целые числа и отрезки (N, N-M, N-, -M), разделенные запятыми")
+	errNegativeFieldValue = errors.New("Номера байтов, символов и полей начинаются с 1")
 	errEmptyFields        = errors.New("Вы не указали ни одного номера столбца для вывода")
+	errOpenRange          = errors.New("У отрезка должен быть указан хотя бы один конец")
+	errDecreasingRange    = errors.New("Начало отрезка больше его конца")
+	errManyLists          = errors.New("Можно указать только один список: байтов (-b), символов (-c) или полей (-f)")
+	errNoList             = errors.New("Укажите список байтов (-b), символов (-c) или полей (-f)")
+	errSeparatedNotFields = errors.New("Флаг -s имеет смысл только при выборе полей (-f)")
+	errNamesWithoutCSV    = errors.New("Выбирать поля по имени столбца можно только в режиме --csv")
+	errCSVNotFields       = errors.New("В режиме --csv можно выбирать только поля (-f)")
+	errCSVDelimeter       = errors.New("В режиме --csv разделитель должен быть одним символом, но не кавычкой и не переводом строки")
+	errRegexDelimeter     = errors.New("Не удалось разобрать регулярное выражение разделителя")
+	errEmptyMatch         = errors.New("Регулярное выражение разделителя не должно совпадать с пустой строкой")
+	errManyDelimeters     = errors.New("Можно указать только один способ разделения полей: -d, --regex-delimiter или -w")
+	errDelimeterNotFields = errors.New("Разделитель имеет смысл только при выборе полей (-f)")
 )
 
+// listMode - что выбирается из строки: байты, символы или поля
+type listMode int
+
+const (
+	modeNone   listMode = iota // Список ещё не указан
+	modeBytes                  // -b: байты, многобайтовый символ UTF-8 может быть разрезан
+	modeChars                  // -c: символы (руны), кириллица не разрезается
+	modeFields                 // -f: поля, разделённые delimeter
+)
+
+// fieldRange - отрезок номеров [lo, hi] байтов, символов или полей, нумерация с 1.
+// Для "N-" hi равен math.MaxInt - до конца строки
+type fieldRange struct {
+	lo, hi int
+}
+
+// fieldSelector - элемент списка полей в том виде и порядке, как его написал пользователь:
+// отрезок номеров или (в режиме --csv) имя столбца из заголовка
+type fieldSelector struct {
+	fieldRange
+	name string // Имя столбца, "" - выбран отрезок
+}
+
 type Options struct { // Структура для изменяющихся опций
-	fields    []int  // Выбранные для вывода колонки
-	delimeter string // По какому разделителю разбиваем на колонки
-	separated bool   // Только строки с разделителем
+	mode               listMode        // Что выбираем: байты, символы или поля
+	list               []fieldRange    // Выбранные для вывода отрезки, по возрастанию и без пересечений
+	selectors          []fieldSelector // Список полей как есть, с повторами и именами: по нему выбираются поля в режиме --csv
+	delimeter          string          // По какому разделителю разбиваем на колонки
+	delimeterSet       bool            // Указан ли delimeter явно (в режиме --csv по умолчанию разделитель - запятая)
+	regex              *regexp.Regexp  // Разделитель - совпадение с регулярным выражением, nil - используется delimeter
+	whitespace         bool            // Поля разделяются любым количеством пробелов и табуляций, как в awk
+	csv                bool            // Разбирать записи по RFC 4180: поля в кавычках, удвоенные кавычки, переводы строк внутри полей
+	outputDelimeter    string          // Чем соединяем выбранные части строки
+	outputDelimeterSet bool            // Указан ли outputDelimeter (пустая строка - тоже значение)
+	complement         bool            // Выводить всё, кроме выбранного
+	separated          bool            // Только строки с разделителем
 }
 
 func GetDefaultOptions() Options { // Функция возвращает нам экземпляр структуры Options, с полями, заполненными значениями по умолчанию
 	return Options{
-		fields:    []int{}, // Номера столбцов, которые необходимо вывести
-		delimeter: "\t",    // В кач-ве разделителя - TAB (по условию задания)
+		mode:      modeNone, // Список байтов, символов или полей обязательно указывает пользователь
+		delimeter: "\t",     // В кач-ве разделителя - TAB (по условию задания)
 		separated: false,
 	}
 }
@@ -30,33 +79,181 @@ var DefaultOptions = GetDefaultOptions() // Присваиваем DefaultOption
 
 type Option func(*Options) error // Значением типа Option будет функция с соответствующей сигнатурой
 
-// Далее идут 3 функции установки конкретной опции (т. е. пОля в структуре Options)
-// Функция, возвращаемая SetFieldsOption, преобразует строку (пользовательский ввод, перечень номеров отбираемых столбцов) к слайсу значений int и присваивает его полю структуры Options
-func SetFieldsOption(fields string) Option { // SetFieldsOption возвращает значение типа Option (функцию с сигнатурой func(*Options) error)
-	return func(o *Options) error { // вход: указатель на значение Options ()
-		resultedFields := []int{} // Этот слайс будем заполнять значениями, представляющими номера отбираемых для вывода столбцов
-		if len(fields) == 0 {     // Так как переданное ф-ции SetFieldsOption при вызове значение - строка, введённая пользователем (перечисление номеров столбцов для выбора), то если на вход SetFieldsOption дали пустую строку...
-			return errEmptyFields // ...вернуть ошибку
-		} // Если длина строки ненулевая, то:
-		fieldsSlice := strings.Split(fields, ",") // Преобразуем строку, введённую пользователем (номера столбцов через запятую) в слайс, разделяя эту строку по сепаратору - запятой
-		for _, f := range fieldsSlice {           // Дальше мы пробегаемся по слайсу и каждый элемент (номер столбца) преобразуем к целочисленному типу...
-			v, err := strconv.Atoi(f) // Преобразование к int может завершиться неуспешно в 2-х случаях:
-			if err != nil {           // 1. Сама ф-ция Atoi завершилась с ошибкой, тогда возвращаем ошибку, сообщающую о невозможности распарсить переданную строку
-				return errUnparsedField
+// parseList преобразует список вида "1,3-5,7-,-2" в отрезки, упорядоченные по началу.
+// Пересекающиеся отрезки объединяются ("2-5,3-7" -> "2-7"), соседние - нет: между ними
+// в режимах -b и -c выводится --output-delimiter
+func parseList(list string) ([]fieldRange, error) {
+	selectors, err := parseSelectors(list)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range selectors {
+		if s.name != "" {
+			return nil, errUnparsedField
+		}
+	}
+	return mergeRanges(selectors), nil
+}
+
+// parseSelectors разбирает список полей, сохраняя порядок и повторы. Элемент, который не удалось прочитать
+// как номер или отрезок, считается именем столбца: "3,имя,1-2" -> отрезок, имя, отрезок
+func parseSelectors(list string) ([]fieldSelector, error) {
+	if len(list) == 0 {
+		return nil, errEmptyFields
+	}
+	var selectors []fieldSelector
+	for _, item := range strings.Split(list, ",") {
+		r, err := parseRange(item)
+		if err == errUnparsedField && item != "" {
+			selectors = append(selectors, fieldSelector{name: item})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, fieldSelector{fieldRange: r})
+	}
+	return selectors, nil
+}
+
+// mergeRanges упорядочивает отрезки из списка полей и объединяет пересекающиеся. Имена столбцов пропускаются
+func mergeRanges(selectors []fieldSelector) []fieldRange {
+	var ranges []fieldRange
+	for _, s := range selectors {
+		if s.name == "" {
+			ranges = append(ranges, s.fieldRange)
+		}
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.lo <= last.hi { // Отрезки пересекаются - расширяем предыдущий
+			if r.hi > last.hi {
+				last.hi = r.hi
 			}
-			if v <= 0 { // 2. Либо пользователем было передано отрицательное число в качестве номера столбца
-				return errNegativeFieldValue
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// parseRange разбирает один элемент списка: "N", "N-M", "N-" или "-M"
+func parseRange(item string) (fieldRange, error) {
+	dash := strings.IndexByte(item, '-')
+	if dash < 0 {
+		n, err := parsePosition(item)
+		return fieldRange{lo: n, hi: n}, err
+	}
+	from, to := item[:dash], item[dash+1:]
+	if from == "" && to == "" {
+		return fieldRange{}, errOpenRange
+	}
+	r := fieldRange{lo: 1, hi: math.MaxInt} // Пропущенное начало - с первого, пропущенный конец - до последнего
+	var err error
+	if from != "" {
+		if r.lo, err = parsePosition(from); err != nil {
+			return fieldRange{}, err
+		}
+	}
+	if to != "" {
+		if r.hi, err = parsePosition(to); err != nil {
+			return fieldRange{}, err
+		}
+	}
+	if r.lo > r.hi {
+		return fieldRange{}, errDecreasingRange
+	}
+	return r, nil
+}
+
+// parsePosition преобразует номер байта, символа или поля к int
+func parsePosition(s string) (int, error) {
+	v, err := strconv.Atoi(s) // Преобразование к int может завершиться неуспешно в 2-х случаях:
+	if err != nil {           // 1. Сама ф-ция Atoi завершилась с ошибкой, тогда возвращаем ошибку, сообщающую о невозможности распарсить переданную строку
+		return 0, errUnparsedField
+	}
+	if v <= 0 { // 2. Либо пользователем было передано неположительное число
+		return 0, errNegativeFieldValue
+	}
+	return v, nil
+}
+
+// setList возвращает Option, устанавливающую список для режима mode. Режим можно выбрать только один
+func setList(mode listMode, list string) Option {
+	return func(o *Options) error {
+		if o.mode != modeNone && o.mode != mode {
+			return errManyLists
+		}
+		if mode != modeFields {
+			ranges, err := parseList(list)
+			if err != nil {
+				return err
 			}
-			resultedFields = append(resultedFields, v-1) // ...затем добавляем этот элемент в результирующий слайс
+			o.mode = mode
+			o.list = ranges
+			return nil
+		}
+		selectors, err := parseSelectors(list) // Имена столбцов проверяет validate: можно ли их использовать, зависит от --csv
+		if err != nil {
+			return err
 		}
-		o.fields = resultedFields // Присваиваем результирующий слайс полю структуры Options
+		o.mode = mode
+		o.selectors = selectors
+		o.list = mergeRanges(selectors)
 		return nil
 	}
 }
 
+// Далее идут функции установки конкретной опции (т. е. пОля в структуре Options)
+// Функция, возвращаемая SetFieldsOption, преобразует строку (пользовательский ввод, перечень номеров и отрезков отбираемых столбцов) к отрезкам и присваивает их полю структуры Options
+func SetFieldsOption(fields string) Option { // SetFieldsOption возвращает значение типа Option (функцию с сигнатурой func(*Options) error)
+	return setList(modeFields, fields)
+}
+
+// SetBytesOption выбирает байты строки (-b)
+func SetBytesOption(bytes string) Option {
+	return setList(modeBytes, bytes)
+}
+
+// SetCharactersOption выбирает символы строки (-c)
+func SetCharactersOption(chars string) Option {
+	return setList(modeChars, chars)
+}
+
 func SetDelimeterOption(delim string) Option {
 	return func(o *Options) error { // Если флаг "delimiter" не будет указан пользователем, параметр delim примет значение по умолчанию,
 		o.delimeter = delim // и в нашей структуре, инициализированной по умолчанию, поле delimiter будет перезаписано тем же значением по умолчанию
+		o.delimeterSet = true
+		return nil
+	}
+}
+
+// SetRegexDelimeterOption разделяет поля совпадениями с регулярным выражением expr (--regex-delimiter), например " +" или "[,;] *".
+// Выражение, совпадающее с пустой строкой, разделило бы строку на отдельные символы, поэтому не принимается
+func SetRegexDelimeterOption(expr string) Option {
+	return func(o *Options) error {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errRegexDelimeter, err)
+		}
+		if re.MatchString("") {
+			return errEmptyMatch
+		}
+		o.regex = re
+		return nil
+	}
+}
+
+// SetWhitespaceOption разделяет поля любым количеством пробелов и табуляций (-w), как awk: пробелы в начале
+// и в конце строки не дают пустых полей, а "a   b" - это два поля, а не четыре
+func SetWhitespaceOption(flag bool) Option {
+	return func(o *Options) error {
+		o.whitespace = flag
 		return nil
 	}
 }
@@ -67,3 +264,144 @@ func SetSeparatedOption(flag bool) Option { // Аналогично
 		return nil
 	}
 }
+
+// SetCSVOption включает разбор записей CSV (--csv): поля в кавычках могут содержать разделитель и переводы строк,
+// выбранные поля выводятся в порядке списка (с повторами) и при необходимости снова заключаются в кавычки.
+// Поля можно выбирать по имени столбца из первой записи каждого файла
+func SetCSVOption(flag bool) Option {
+	return func(o *Options) error {
+		o.csv = flag
+		return nil
+	}
+}
+
+// SetComplementOption - выводить всё, кроме выбранных байтов, символов или полей (--complement)
+func SetComplementOption(flag bool) Option {
+	return func(o *Options) error {
+		o.complement = flag
+		return nil
+	}
+}
+
+// SetOutputDelimeterOption задаёт, чем соединять выбранные части строки (--output-delimiter).
+// По умолчанию поля соединяются входным разделителем, а отрезки байтов и символов - ничем
+func SetOutputDelimeterOption(delim string) Option {
+	return func(o *Options) error {
+		o.outputDelimeter = delim
+		o.outputDelimeterSet = true
+		return nil
+	}
+}
+
+// validate проверяет, что настройки согласованы между собой
+func (o *Options) validate() error {
+	if o.mode == modeNone {
+		return errNoList
+	}
+	if o.separated && o.mode != modeFields {
+		return errSeparatedNotFields
+	}
+	splitters := 0 // Сколько способов разделения полей указано
+	for _, set := range []bool{o.delimeterSet, o.regex != nil, o.whitespace} {
+		if set {
+			splitters++
+		}
+	}
+	if splitters > 1 {
+		return errManyDelimeters
+	}
+	if splitters > 0 && o.mode != modeFields {
+		return errDelimeterNotFields
+	}
+	if o.csv {
+		if o.regex != nil || o.whitespace {
+			return errCSVDelimeter
+		}
+		if o.mode != modeFields {
+			return errCSVNotFields
+		}
+		delim := o.fieldDelimeter()
+		if utf8.RuneCountInString(delim) != 1 || delim == `"` || delim == "\n" || delim == "\r" {
+			return errCSVDelimeter
+		}
+		return nil
+	}
+	for _, s := range o.selectors {
+		if s.name != "" {
+			return errNamesWithoutCSV
+		}
+	}
+	return nil
+}
+
+// fieldDelimeter возвращает разделитель полей: в режиме --csv, если -d не указан, это запятая
+func (o *Options) fieldDelimeter() string {
+	if o.csv && !o.delimeterSet {
+		return ","
+	}
+	return o.delimeter
+}
+
+// joiner возвращает, чем соединять выбранные части строки
+func (o *Options) joiner() string {
+	if o.outputDelimeterSet {
+		return o.outputDelimeter
+	}
+	if o.mode == modeFields {
+		if o.regex != nil || o.whitespace { // Найденные разделители могут быть разными, выводим вместо них пробел
+			return " "
+		}
+		return o.fieldDelimeter()
+	}
+	return ""
+}
+
+// splitFields делит строку на поля. false - в строке нет ни одного разделителя
+func (o *Options) splitFields(line string) ([]string, bool) {
+	switch {
+	case o.whitespace:
+		fields := strings.FieldsFunc(line, isBlank) // Не strings.Fields: неразрывный пробел и прочие пробельные символы юникода - часть поля
+		return fields, len(fields) > 1
+	case o.regex != nil:
+		fields := o.regex.Split(line, -1)
+		return fields, len(fields) > 1
+	}
+	if !strings.Contains(line, o.delimeter) {
+		return nil, false
+	}
+	return strings.Split(line, o.delimeter), true
+}
+
+// isBlank сообщает, разделяет ли r поля при -w
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// segments возвращает выбранные части строки из n байтов, символов или полей: полуинтервалы [from, to)
+// с нумерацией с 0, по возрастанию. С complement выбирается всё, что не попало в список
+func (o *Options) segments(n int) [][2]int {
+	var res [][2]int
+	pos := 0 // Для complement: начало ещё не выбранной части
+	for _, r := range o.list {
+		from := r.lo - 1
+		if from >= n {
+			break
+		}
+		to := n
+		if r.hi < n {
+			to = r.hi
+		}
+		if !o.complement {
+			res = append(res, [2]int{from, to})
+		} else {
+			if from > pos {
+				res = append(res, [2]int{pos, from})
+			}
+			pos = to
+		}
+	}
+	if o.complement && pos < n {
+		res = append(res, [2]int{pos, n})
+	}
+	return res
+}
@@ -3,51 +3,166 @@ package managers
 import (
 	"bufio"
 	"errors"
-	"fmt"
 	"io"
-	"strings"
+	"os"
 )
 
-var (
-	errConsoleInput = errors.New("Не удалось прочитать данные из ст. ввода")
-)
+// FileError - ошибка открытия или чтения одного из входных файлов. Она относится только к этому файлу:
+// чтение можно продолжить со следующего
+type FileError struct {
+	Name string
+	Err  error
+}
 
-// ConsoleManager пишет/читает В и ИЗ stdout/stdin
-// ConsoleManager (а точнее *ConsoleManager) имеет методы Read и Write
-type ConsoleManager struct { // Сущность ConsoleManager имеет поля интерфейсного типа, значит могут принимать значения любых соответствующих
-	writer io.Writer		 // этим интерфейсам типов (имеющих методы Write и Read соответственно)
-	reader io.Reader
+func (e *FileError) Error() string {
+	return e.Name + ": " + e.Err.Error()
 }
 
-// NewConsoleManager  - конструктор, создает значение ConsoleManager, возвращает указатель на него
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// ConsoleManager построчно читает stdin или файлы и пишет результат в stdout
+// ConsoleManager (а точнее *ConsoleManager) имеет методы ReadLine и WriteLine
+type ConsoleManager struct {
+	writer *bufio.Writer // Результат буферизуется и сбрасывается перед каждым чтением, которое может заблокироваться
+	stdin  io.Reader     // Что читать вместо файла "-"
+	names  []string      // Ещё не открытые входные файлы
+	name   string        // Текущий файл
+	number int           // Номер текущего файла, считая с 1
+	reader *bufio.Reader // Текущий источник строк, nil - нужно открыть следующий файл
+	file   *os.File      // Открытый текущий файл, nil для stdin
+}
+
+// NewConsoleManager  - конструктор, создает значение ConsoleManager, читающее reader, и возвращает указатель на него
 func NewConsoleManager(reader io.Reader, writer io.Writer) *ConsoleManager { // Когда будем вызывать эту функцию, передадим ей os.Stdin, os.Stdout,
-	return &ConsoleManager{                                                  // имеющие методы Read/Write соответственно
-		writer: writer,
-		reader: reader,
+	return NewFilesManager(nil, reader, writer) // имеющие методы Read/Write соответственно
+}
+
+// NewFilesManager создаёт ConsoleManager, читающий файлы names по очереди. Имя "-" и пустой список означают stdin
+func NewFilesManager(names []string, stdin io.Reader, writer io.Writer) *ConsoleManager {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	return &ConsoleManager{
+		writer: bufio.NewWriter(writer),
+		stdin:  stdin,
+		names:  names,
 	}
 }
 
-// Read читает из stdin, возвращает слайс строк, где каждый элемент - строка пользовательского ввода
-func (cm *ConsoleManager) Read() ([]string, error) { // Получатель метода - указатель на значение ConsoleManager
-	data := make([]string, 0)
-	scanner := bufio.NewScanner(cm.reader) // Создать "буферизованное средство чтения"
+// ReadLine возвращает очередную строку без перевода строки. Последняя строка файла без "\n" - тоже строка,
+// пустые строки возвращаются как есть. Когда строки во всех файлах закончились, возвращает io.EOF.
+// Ошибки открытия и чтения файлов возвращаются как *FileError: следующий вызов продолжит со следующего файла
+func (cm *ConsoleManager) ReadLine() (string, error) {
 	for {
-		scanner.Scan() // Читаем построчно...
-		text := scanner.Text()
-		if len(text) != 0 {
-			data = append(data, text) // Каждое прочитанное строковое значение добавляем в слайс
-		} else { // ...до тех пор, пока очередной строкой не будет прочитана ПУСТАЯ строка
-			break
+		if err := cm.flushBeforeBlocking(); err != nil {
+			return "", err
+		}
+		if cm.reader == nil {
+			if len(cm.names) == 0 {
+				return "", io.EOF
+			}
+			name := cm.names[0]
+			cm.names = cm.names[1:]
+			if err := cm.open(name); err != nil {
+				return "", err
+			}
+		}
+		line, err := cm.reader.ReadString('\n')
+		switch {
+		case err == nil:
+			return line[:len(line)-1], nil
+		case errors.Is(err, io.EOF):
+			cm.closeCurrent()
+			if line != "" {
+				return line, nil
+			}
+		default:
+			name := cm.name
+			cm.closeCurrent()
+			return "", &FileError{Name: name, Err: unwrapPath(err)}
 		}
 	}
-	if scanner.Err() != nil {
-		return nil, errConsoleInput
+}
+
+// flushBeforeBlocking выводит готовый результат, если следующее чтение может заблокироваться
+// (например, в ожидании ввода с клавиатуры) или выдать ошибку
+func (cm *ConsoleManager) flushBeforeBlocking() error {
+	if cm.reader == nil || cm.reader.Buffered() == 0 {
+		return cm.writer.Flush()
 	}
-	return data, nil // Этот метод может вернуть в кач-ве значения data пустую строку, => надо будет обработать ошибку при его вызове
+	return nil
+}
+
+// Name возвращает имя файла, из которого прочитана последняя строка
+func (cm *ConsoleManager) Name() string {
+	return cm.name
+}
+
+// FileNumber возвращает номер файла, из которого прочитана последняя строка, считая с 1.
+// По нему видно, что начался следующий файл, даже если имена файлов повторяются
+func (cm *ConsoleManager) FileNumber() int {
+	return cm.number
 }
 
-// Write выводит результат (значения поля result структуры ManCut) в stdout
-func (cm *ConsoleManager) Write(data []string) error {
-	_, err := fmt.Fprintln(cm.writer, strings.Join(data, "\n")) // В нашем случае работаем с stdout (cm.writer это io.stdout)
+// EndOfFile сообщает, была ли последняя прочитанная строка последней в своём файле.
+// Чтобы это узнать, может понадобиться дождаться следующей строки
+func (cm *ConsoleManager) EndOfFile() bool {
+	if cm.reader == nil {
+		return true
+	}
+	cm.flushBeforeBlocking() // Ошибку вывода вернёт следующий ReadLine или Close
+	_, err := cm.reader.Peek(1)
+	return err != nil
+}
+
+// open делает файл name текущим источником строк
+func (cm *ConsoleManager) open(name string) error {
+	cm.name = name
+	cm.number++
+	if name == "-" {
+		cm.reader = bufio.NewReader(cm.stdin)
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return &FileError{Name: name, Err: unwrapPath(err)}
+	}
+	cm.file = f
+	cm.reader = bufio.NewReader(f)
+	return nil
+}
+
+// closeCurrent закрывает текущий файл: следующий ReadLine откроет следующий
+func (cm *ConsoleManager) closeCurrent() {
+	if cm.file != nil {
+		cm.file.Close()
+		cm.file = nil
+	}
+	cm.reader = nil
+}
+
+// unwrapPath убирает из ошибки операцию и имя файла: имя и так есть в FileError
+func unwrapPath(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
 	return err
 }
+
+// WriteLine выводит строку результата и перевод строки
+func (cm *ConsoleManager) WriteLine(line string) error {
+	if _, err := cm.writer.WriteString(line); err != nil {
+		return err
+	}
+	return cm.writer.WriteByte('\n')
+}
+
+// Close выводит остаток результата и закрывает текущий файл, если чтение прервано до конца входных данных
+func (cm *ConsoleManager) Close() error {
+	cm.closeCurrent()
+	cm.names = nil
+	return cm.writer.Flush()
+}
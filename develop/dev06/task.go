@@ -1,13 +1,23 @@
 /*
 === Утилита cut ===
 
-Принимает STDIN, разбивает по разделителю (TAB) на колонки, выводит запрошенные
+Принимает STDIN (или файлы), разбивает по разделителю (TAB) на колонки, выводит запрошенные
 
 Поддержать флаги:
 -f - "fields" - выбрать поля (колонки)
 -d - "delimiter" - использовать другой разделитель
 -s - "separated" - только строки с разделителем
 
+Дополнительно:
+-b, -c - выбрать байты или символы (кириллица считается по символам, а не по байтам)
+списки для -b, -c и -f - номера и отрезки через запятую: N, N-M, N-, -M
+--complement - выводить всё, кроме выбранного
+--output-delimiter - чем соединять выбранные части строки
+--regex-delimiter - разделять поля совпадениями с регулярным выражением
+-w - разделять поля любым количеством пробелов и табуляций, как awk
+--csv, --tsv - разбирать CSV (по умолчанию через запятую) или TSV по RFC 4180: поля в кавычках, переводы строк внутри полей;
+               поля можно выбирать по имени столбца из заголовка, переставлять и повторять ("-f имя,3,1,1")
+
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
@@ -22,21 +32,37 @@ import (
 )
 
 var (
-	fields      string
-	delimeter   string
-	isSeparated bool
-	help        bool
+	bytesList       string
+	charsList       string
+	fields          string
+	delimeter       string
+	outputDelimeter string
+	regexDelimeter  string
+	isSeparated     bool
+	complement      bool
+	whitespace      bool
+	csvMode         bool
+	tsvMode         bool
+	help            bool
 )
 
 func init() { // init() запускается сразу же после импорта пакета, используется при необходимости инициализации приложения в определенном состоянии
-	flag.StringVar(&fields, "f", "", "Выбрать поля (колонки). Целые положительные числа, через запятую в кавычках")
-	flag.StringVar(&delimeter, "d", "\t", "Использовать другой разделитель")
+	flag.StringVar(&bytesList, "b", "", "Выбрать байты. Номера и отрезки (N, N-M, N-, -M) через запятую")
+	flag.StringVar(&charsList, "c", "", "Выбрать символы. Номера и отрезки (N, N-M, N-, -M) через запятую")
+	flag.StringVar(&fields, "f", "", "Выбрать поля (колонки). Номера и отрезки (N, N-M, N-, -M) через запятую, в режиме --csv - и имена столбцов")
+	flag.StringVar(&delimeter, "d", "\t", "Использовать другой разделитель (в режиме --csv по умолчанию - запятая)")
+	flag.StringVar(&outputDelimeter, "output-delimiter", "", "Соединять выбранные части строки этой строкой (по умолчанию для полей - разделителем из -d)")
+	flag.StringVar(&regexDelimeter, "regex-delimiter", "", "Разделять поля совпадениями с регулярным выражением (вместо -d)")
+	flag.BoolVar(&whitespace, "w", false, "Разделять поля любым количеством пробелов и табуляций, как awk (вместо -d)")
 	flag.BoolVar(&isSeparated, "s", false, "Выводить только строки с разделителем")
+	flag.BoolVar(&complement, "complement", false, "Выводить всё, кроме выбранных байтов, символов или полей")
+	flag.BoolVar(&csvMode, "csv", false, "Разбирать CSV: поля в кавычках, выбор полей по имени столбца, в порядке списка и с повторами")
+	flag.BoolVar(&tsvMode, "tsv", false, "То же, что --csv с разделителем TAB")
 	flag.BoolVar(&help, "help", false, "Показать помощь и выйти.")
 }
 
 func usage() {
-	log.Printf(`Уитилита для обрезки строк. Использование: ./[название_исполняемого_файла] [опции]
+	log.Printf(`Уитилита для обрезки строк. Использование: ./[название_исполняемого_файла] [опции] [файл...]
 Доступные опции:`)
 	flag.PrintDefaults() // Выводит доступные флаги, их usage и дефолтное значение
 }
@@ -55,23 +81,35 @@ func main() {
 		showUsageAndExit(0)
 	}
 
-	conManager := managers.NewConsoleManager(os.Stdin, os.Stdout) // В нашем случае читать будем из stdin.
+	conManager := managers.NewFilesManager(flag.Args(), os.Stdin, os.Stdout) // Читаем файлы из аргументов по очереди, без аргументов (или вместо "-") - stdin.
 	// Stdin, Stdout - это открытые файлы, указывающие на файловые дескрипторы стандартных ввода и вывода
 	options := []cut.Option{ // Здесь с помощью литерала слайса создаём слайс значений Option (функций с сигнатурой "func(o *Options) error")
-		cut.SetFieldsOption(fields),         // Здесь каждая
-		cut.SetDelimeterOption(delimeter),   // инструкция вернёт
-		cut.SetSeparatedOption(isSeparated), // по функции (по значению типа Option)
-		// Аргументы, передаваемые этим трём функциям, взяты из пользовательского ввода ()
-		// Если один/несколько/все из этих аргументов - значения по умолчанию для флагов, то:
-		// 1. ф-ция, возвращённая ф-цией SetFieldsOption, если дать ей на вход "", вернёт ошибку
+		cut.SetSeparatedOption(isSeparated),  // Каждая инструкция
+		cut.SetComplementOption(complement),  // вернёт по функции
+		cut.SetWhitespaceOption(whitespace),  // (по значению
+		cut.SetCSVOption(csvMode || tsvMode), // типа Option)
 	}
+	flag.Visit(func(f *flag.Flag) { // Списки и разделители добавляем, только если пользователь указал соответствующий флаг: у списков нет
+		switch f.Name { // осмысленного значения по умолчанию, разделитель по умолчанию зависит от --csv, а пустой выходной разделитель - тоже значение
+		case "d":
+			options = append(options, cut.SetDelimeterOption(delimeter))
+		case "regex-delimiter":
+			options = append(options, cut.SetRegexDelimeterOption(regexDelimeter))
+		case "tsv":
+			options = append(options, cut.SetDelimeterOption("\t"))
+		case "b":
+			options = append(options, cut.SetBytesOption(bytesList))
+		case "c":
+			options = append(options, cut.SetCharactersOption(charsList))
+		case "f":
+			options = append(options, cut.SetFieldsOption(fields))
+		case "output-delimiter":
+			options = append(options, cut.SetOutputDelimeterOption(outputDelimeter))
+		}
+	})
 
 	newCut := cut.New(conManager).ApplyOptions(options...) // Создаём новый экземпляр ManCut, передаем ему менеджера, применяем нужные опции (вызываем все функции из слайса)
-	if err := newCut.Cut(); err != nil {                   // Здесь собственно и вызываем метод "Cut" типа ManCut
-		log.Fatal(err)
-	}
-
-	if err := newCut.OutputResult(); err != nil {
+	if err := newCut.Cut(); err != nil {                   // Здесь собственно и вызываем метод "Cut" типа ManCut: он читает строки и сразу выводит результат
 		log.Fatal(err)
 	}
 }
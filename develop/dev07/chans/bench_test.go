@@ -0,0 +1,104 @@
+package chans
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// orRecursive - исходная рекурсивная реализация or из task.go. Каждая горутина ждёт три канала и результат
+// вложенного вызова, которому достаются остальные каналы и её собственный orDone. Так уровень вложенности
+// убирает из списка лишь два канала, и на n каналов горутин получается около n/2. Оставлена для сравнения в бенчмарках
+func orRecursive(channs ...<-chan interface{}) <-chan interface{} {
+	switch len(channs) {
+	case 0:
+		return nil
+	case 1:
+		return channs[0]
+	}
+	orDone := make(chan interface{})
+	go func() {
+		defer close(orDone)
+		switch len(channs) {
+		case 2:
+			select {
+			case <-channs[0]:
+			case <-channs[1]:
+			}
+		default:
+			select {
+			case <-channs[0]:
+			case <-channs[1]:
+			case <-channs[2]:
+			case <-orRecursive(append(channs[3:], orDone)...):
+			}
+		}
+	}()
+	return orDone
+}
+
+// benchSizes - от десятков done-каналов до сотни тысяч, как у супервизора задач
+var benchSizes = []int{10, 100, 1000, 10000, 100000}
+
+// benchmarkOr измеряет, сколько стоит объединить n done-каналов и дождаться закрытия последнего из них.
+// Время и память - на создание or-канала и ожидание, latency-ns/op - от закрытия канала до закрытия результата.
+// B/op не учитывает стеки горутин: их у Or по одной на orGroup каналов, у рекурсивной версии - около n/2
+// (по три канала на горутину, но в каждый вложенный вызов добавляется orDone), у OrSelect - одна на selectLimit. Их показывает stack-B/op: сколько стека занято, пока or-канал ждёт
+// (рекурсивная версия запускает горутины постепенно, поэтому для неё это оценка снизу)
+func benchmarkOr(b *testing.B, or func(...<-chan interface{}) <-chan interface{}) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var latency time.Duration
+			var stack uint64
+			var mem runtime.MemStats
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				chs := make([]chan interface{}, n)
+				recv := make([]<-chan interface{}, n)
+				for j := range chs {
+					chs[j] = make(chan interface{})
+					recv[j] = chs[j]
+				}
+				runtime.ReadMemStats(&mem)
+				stackBefore := mem.StackInuse
+				b.StartTimer()
+				done := or(recv...)
+				b.StopTimer()
+				runtime.ReadMemStats(&mem)
+				stack += mem.StackInuse - stackBefore
+				b.StartTimer()
+				start := time.Now()
+				close(chs[n-1]) // Последний канал - самый глубокий уровень рекурсии
+				<-done
+				latency += time.Since(start)
+			}
+			b.ReportMetric(float64(latency.Nanoseconds())/float64(b.N), "latency-ns/op")
+			b.ReportMetric(float64(stack)/float64(b.N), "stack-B/op")
+		})
+	}
+}
+
+func BenchmarkOr(b *testing.B) {
+	benchmarkOr(b, func(chs ...<-chan interface{}) <-chan interface{} {
+		return Or(context.Background(), chs...)
+	})
+}
+
+func BenchmarkOrSelect(b *testing.B) {
+	benchmarkOr(b, func(chs ...<-chan interface{}) <-chan interface{} {
+		return OrSelect(context.Background(), chs...)
+	})
+}
+
+func BenchmarkOrHybrid(b *testing.B) {
+	benchmarkOr(b, func(chs ...<-chan interface{}) <-chan interface{} {
+		return OrHybrid(context.Background(), chs...)
+	})
+}
+
+func BenchmarkOrRecursive(b *testing.B) {
+	benchmarkOr(b, orRecursive)
+}
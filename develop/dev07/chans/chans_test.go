@@ -0,0 +1,288 @@
+package chans
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkLeaks проверяет в конце теста, что все горутины, запущенные во время теста, завершились.
+// Горутины завершаются асинхронно, поэтому даём им немного времени
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("горутин %d, а до теста было %d:\n%s", runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+// isClosed сообщает, закрылся ли канал done за время wait
+func isClosed[T any](done <-chan T, wait time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+// openChannels создаёт n каналов, которые закрывает сам тест
+func openChannels(n int) ([]chan struct{}, []<-chan struct{}) {
+	chs := make([]chan struct{}, n)
+	recv := make([]<-chan struct{}, n)
+	for i := range chs {
+		chs[i] = make(chan struct{})
+		recv[i] = chs[i]
+	}
+	return chs, recv
+}
+
+// orImplementations - реализации or-канала, которые должны вести себя одинаково
+var orImplementations = map[string]func(context.Context, ...<-chan struct{}) <-chan struct{}{
+	"Or":       Or[struct{}],
+	"OrSelect": OrSelect[struct{}],
+	"OrHybrid": OrHybrid[struct{}],
+}
+
+func TestOr(t *testing.T) {
+	for name, or := range orImplementations {
+		t.Run(name, func(t *testing.T) {
+			for _, n := range []int{1, 2, 3, 4, 5, 9, 100, hybridThreshold + 1} {
+				for _, closed := range []int{0, n / 2, n - 1} {
+					checkLeaks(t)
+					chs, recv := openChannels(n)
+					done := or(context.Background(), recv...)
+					if isClosed(done, time.Millisecond) {
+						t.Fatalf("n=%d: закрыт до закрытия входов", n)
+					}
+					close(chs[closed])
+					if !isClosed(done, time.Second) {
+						t.Fatalf("n=%d: не закрыт после закрытия канала %d", n, closed)
+					}
+					// Остальные каналы так и остаются открытыми, но горутины or-канала уже завершились: это проверит checkLeaks
+				}
+			}
+		})
+	}
+}
+
+func TestOrSelectManyChannels(t *testing.T) {
+	n := selectLimit*2 + 10 // Три горутины: больше 65536 вариантов reflect.Select не принимает
+	for _, closed := range []int{0, selectLimit + 1, n - 1} {
+		checkLeaks(t)
+		chs, recv := openChannels(n)
+		done := OrSelect(context.Background(), recv...)
+		close(chs[closed])
+		if !isClosed(done, 5*time.Second) {
+			t.Fatalf("не закрыт после закрытия канала %d", closed)
+		}
+	}
+}
+
+func TestOrValueAndContext(t *testing.T) {
+	for name, or := range orImplementations {
+		t.Run(name, func(t *testing.T) {
+			checkLeaks(t)
+			values := make(chan struct{}, 1)
+			values <- struct{}{}
+			if !isClosed(or(context.Background(), make(chan struct{}), values), time.Second) {
+				t.Error("значение в канале должно закрывать результат")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			_, recv := openChannels(10)
+			done := or(ctx, recv...)
+			cancel()
+			if !isClosed(done, time.Second) {
+				t.Error("не закрыт после отмены контекста")
+			}
+
+			ctx, cancel = context.WithCancel(context.Background())
+			done = or(ctx)
+			if isClosed(done, 10*time.Millisecond) {
+				t.Error("без каналов закрыт до отмены контекста")
+			}
+			cancel()
+			if !isClosed(done, time.Second) {
+				t.Error("без каналов не закрыт после отмены контекста")
+			}
+		})
+	}
+}
+
+func TestAnd(t *testing.T) {
+	checkLeaks(t)
+	chs, recv := openChannels(5)
+	done := And(context.Background(), recv...)
+	chs[2] <- struct{}{} // Значения не мешают ждать закрытия
+	for i, ch := range chs[:4] {
+		close(ch)
+		if isClosed(done, 10*time.Millisecond) {
+			t.Fatalf("закрыт, когда закрыто только %d каналов из 5", i+1)
+		}
+	}
+	close(chs[4])
+	if !isClosed(done, time.Second) {
+		t.Fatal("не закрыт после закрытия всех каналов")
+	}
+
+	if !isClosed(And[int](context.Background()), time.Second) {
+		t.Error("без каналов должен быть закрыт сразу")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, recv = openChannels(3)
+	done = And(ctx, recv...)
+	cancel()
+	if !isClosed(done, time.Second) {
+		t.Error("не закрыт после отмены контекста")
+	}
+}
+
+func TestFirstValue(t *testing.T) {
+	checkLeaks(t)
+	empty := make(chan string)
+	close(empty)
+	silent := make(chan string) // Никогда ничего не передаёт и не закрывается
+	values := make(chan string, 1)
+	values <- "первое"
+
+	v, err := FirstValue(context.Background(), empty, silent, values)
+	if err != nil || v != "первое" {
+		t.Errorf("actual %q, %v, expected %q", v, err, "первое")
+	}
+
+	if _, err := FirstValue(context.Background(), empty, empty); err != ErrNoValue {
+		t.Errorf("actual %v, expected %v", err, ErrNoValue)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := FirstValue(ctx, silent, empty); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("actual %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+// generate возвращает канал со значениями values, который закрывается после них
+func generate[T any](values ...T) <-chan T {
+	ch := make(chan T, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+func TestMerge(t *testing.T) {
+	checkLeaks(t)
+	sum := 0
+	count := 0
+	for v := range Merge(context.Background(), generate(1, 2, 3), generate[int](), generate(10, 20)) {
+		sum += v
+		count++
+	}
+	if sum != 36 || count != 5 {
+		t.Errorf("actual sum %d, count %d, expected 36, 5", sum, count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := Merge(ctx, generate(1, 2, 3), make(chan int)) // Результат не дочитываем, второй вход не закрывается
+	<-out
+	cancel()
+	for range out { // После отмены результат закрывается (может успеть прийти ещё одно значение)
+	}
+}
+
+func TestTee(t *testing.T) {
+	checkLeaks(t)
+	out1, out2 := Tee(context.Background(), generate("а", "б", "в"))
+	var got1, got2 []string
+	for out1 != nil || out2 != nil {
+		select {
+		case v, ok := <-out1:
+			if !ok {
+				out1 = nil
+				continue
+			}
+			got1 = append(got1, v)
+		case v, ok := <-out2:
+			if !ok {
+				out2 = nil
+				continue
+			}
+			got2 = append(got2, v)
+		}
+	}
+	expected := []string{"а", "б", "в"}
+	if !reflect.DeepEqual(got1, expected) || !reflect.DeepEqual(got2, expected) {
+		t.Errorf("actual %q и %q, expected %q", got1, got2, expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rest, unread := Tee(ctx, generate(1, 2))
+	<-rest // Второй результат не читаем: Tee ждёт его, пока не отменён контекст
+	cancel()
+	for range unread {
+	}
+}
+
+func TestBridge(t *testing.T) {
+	checkLeaks(t)
+	streams := make(chan (<-chan int), 3)
+	streams <- generate(1, 2)
+	streams <- generate[int]()
+	streams <- generate(3)
+	close(streams)
+	var got []int
+	for v := range Bridge(context.Background(), streams) {
+		got = append(got, v)
+	}
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("actual %v, expected %v", got, expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	endless := make(chan (<-chan int), 1)
+	endless <- make(chan int) // Канал, который никогда не закроется
+	out := Bridge(ctx, endless)
+	cancel()
+	if !isClosed(out, time.Second) {
+		t.Error("не закрыт после отмены контекста")
+	}
+}
+
+func TestOrDone(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case in <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	n := 0
+	for range OrDone(ctx, in) {
+		if n++; n == 10 {
+			cancel()
+		}
+	}
+	if n < 10 {
+		t.Errorf("прочитано %d значений, ожидалось не меньше 10", n)
+	}
+}
@@ -0,0 +1,118 @@
+// Package chans - комбинаторы каналов: or- и and-каналы, первое значение, слияние, тройник и мост.
+// Все функции принимают context.Context и не оставляют горутин: каждая запущенная горутина завершается,
+// как только результат готов, входные каналы закрыты или ctx отменён
+package chans
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoValue - все каналы закрылись, так и не передав ни одного значения
+var ErrNoValue = errors.New("все каналы закрыты, значений не было")
+
+// orGroup - сколько каналов ждёт одна горутина Or. select по фиксированному числу каналов не требует рекурсии,
+// а недостающие каналы заменяются nil: из nil-канала select никогда не читает
+const orGroup = 4
+
+// Or возвращает канал, который закрывается, как только из любого канала channels удалось прочитать
+// (пришло значение или канал закрыт), либо отменён ctx - отличить одно от другого поможет ctx.Err().
+// Прочитанное значение теряется, поэтому каналы должны быть done-каналами, а не каналами данных.
+// Горутин запускается по одной на orGroup каналов, и все они завершаются вместе с результатом,
+// даже если остальные каналы не закроются никогда
+func Or[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	done := make(chan T)
+	if len(channels) == 0 && ctx.Done() == nil { // Закрыть результат нечему: не запускаем горутину, которая ждала бы вечно
+		return done
+	}
+	var once sync.Once
+	closeDone := func() { once.Do(func() { close(done) }) }
+	if len(channels) == 0 {
+		go func() {
+			<-ctx.Done()
+			closeDone()
+		}()
+		return done
+	}
+	for i := 0; i < len(channels); i += orGroup {
+		group := make([]<-chan T, orGroup)
+		copy(group, channels[i:])
+		go func() {
+			select {
+			case <-group[0]:
+			case <-group[1]:
+			case <-group[2]:
+			case <-group[3]:
+			case <-ctx.Done():
+			case <-done: // Результат закрыла другая горутина - больше ждать нечего
+				return
+			}
+			closeDone()
+		}()
+	}
+	return done
+}
+
+// And возвращает канал, который закрывается, когда закрыты все каналы channels, либо отменён ctx.
+// Значения, пришедшие до закрытия, читаются и отбрасываются, чтобы отправители не блокировались.
+// Без каналов результат закрыт сразу
+func And[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	done := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(channels))
+	for _, ch := range channels {
+		// Каждый канал читаем в своей горутине: ожидание по очереди заблокировало бы отправителей в ещё не прочитанные каналы
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for {
+				if _, ok := Receive(ctx, ch); !ok {
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// FirstValue возвращает первое значение, пришедшее из любого канала channels. Закрытые каналы пропускаются;
+// если закрылись все, возвращается ErrNoValue, если раньше отменён ctx - ctx.Err().
+// Из каждого канала читается не больше одного значения, но значения, прочитанные одновременно с первым, теряются
+func FirstValue[T any](ctx context.Context, channels ...<-chan T) (T, error) {
+	mergeCtx, cancel := context.WithCancel(ctx)
+	defer cancel() // Останавливаем горутины Merge, как только первое значение получено
+	if v, ok := Receive(mergeCtx, Merge(mergeCtx, channels...)); ok {
+		return v, nil
+	}
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	return zero, ErrNoValue
+}
+
+// Receive читает значение из in. false - in закрыт или ctx отменён.
+// На Receive и Send строятся стадии, которые должны останавливаться по отмене контекста
+func Receive[T any](ctx context.Context, in <-chan T) (T, bool) {
+	select {
+	case v, ok := <-in:
+		return v, ok
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+// Send отправляет v в out. false - ctx отменён раньше, чем значение забрали
+func Send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
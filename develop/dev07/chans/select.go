@@ -0,0 +1,54 @@
+package chans
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// selectLimit - сколько каналов ждёт одна горутина OrSelect: reflect.Select принимает не больше 65536 вариантов,
+// и два из них заняты закрытием результата и отменой ctx
+const selectLimit = 1<<16 - 2
+
+// hybridThreshold - с какого количества каналов OrHybrid переходит на OrSelect (подобрано по BenchmarkOr*)
+const hybridThreshold = 512
+
+// OrSelect - то же, что Or, но ждёт каналы через reflect.Select: одна горутина на каждые selectLimit каналов
+// вместо одной на orGroup. На тысячах каналов это экономит память под стеки горутин,
+// но каждый вызов reflect.Select сам по себе дороже обычного select
+func OrSelect[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	if len(channels) == 0 {
+		return Or[T](ctx) // Ждать нечего, кроме ctx
+	}
+	done := make(chan T)
+	var once sync.Once
+	for i := 0; i < len(channels); i += selectLimit {
+		end := i + selectLimit
+		if end > len(channels) {
+			end = len(channels)
+		}
+		cases := make([]reflect.SelectCase, 0, end-i+2)
+		cases = append(cases,
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)}, // Результат закрыла другая горутина
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		)
+		for _, ch := range channels[i:end] {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+		}
+		go func() {
+			if chosen, _, _ := reflect.Select(cases); chosen != 0 {
+				once.Do(func() { close(done) })
+			}
+		}()
+	}
+	return done
+}
+
+// OrHybrid выбирает реализацию по количеству каналов: до hybridThreshold - Or, у которого меньше задержка
+// и накладные расходы на малых n, дальше - OrSelect, которому не нужны тысячи горутин
+func OrHybrid[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	if len(channels) < hybridThreshold {
+		return Or(ctx, channels...)
+	}
+	return OrSelect(ctx, channels...)
+}
@@ -0,0 +1,100 @@
+package chans
+
+import (
+	"context"
+	"sync"
+)
+
+// OrDone возвращает канал со значениями из in, который закрывается, когда закрыт in или отменён ctx.
+// Так цикл range по чужому каналу можно прервать отменой контекста
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := Receive(ctx, in)
+			if !ok || !Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Merge сливает значения из всех каналов channels в один (fan-in). Порядок значений из разных каналов
+// не определён, из одного канала - сохраняется. Результат закрывается, когда закрыты все каналы или отменён ctx
+func Merge[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(channels))
+	for _, ch := range channels {
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for {
+				v, ok := Receive(ctx, ch)
+				if !ok || !Send(ctx, out, v) {
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee передаёт каждое значение из in в оба результата. Следующее значение читается из in, только когда
+// предыдущее забрали из обоих результатов, поэтому читать их нужно одновременно.
+// Оба результата закрываются, когда закрыт in или отменён ctx
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for {
+			v, ok := Receive(ctx, in)
+			if !ok {
+				return
+			}
+			first, second := out1, out2
+			for i := 0; i < 2; i++ { // Отправляем в тот результат, который готов первым, затем во второй
+				select {
+				case first <- v:
+					first = nil // Отправка в nil-канал никогда не готова: второй раз в этот результат не пишем
+				case second <- v:
+					second = nil
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge превращает канал каналов в один канал значений: читает каналы по очереди, каждый - до закрытия.
+// Результат закрывается, когда закрыт chanStream или отменён ctx
+func Bridge[T any](ctx context.Context, chanStream <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			stream, ok := Receive(ctx, chanStream)
+			if !ok {
+				return
+			}
+			for {
+				v, ok := Receive(ctx, stream)
+				if !ok {
+					break
+				}
+				if !Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,3 @@
+module dev07
+
+go 1.18
@@ -0,0 +1,70 @@
+// Package pipeline - стадии конвейеров на каналах: генераторы, map/filter, пулы воркеров с упорядоченным
+// и неупорядоченным результатом, ограничение частоты. Стадии останавливаются по отмене контекста,
+// а контекст можно связать с done-каналами (WithDone) и с ошибками стадий (Group)
+package pipeline
+
+import (
+	"context"
+	"dev07/chans"
+	"sync"
+)
+
+// WithDone возвращает контекст, который отменяется, как только закроется любой из done-каналов
+// (они объединяются chans.Or), отменён parent или вызвана cancel. Горутина, которая ждёт каналы,
+// завершается вместе с контекстом, поэтому cancel нужно вызвать, когда конвейер больше не нужен
+func WithDone(parent context.Context, dones ...<-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if len(dones) == 0 {
+		return ctx, cancel
+	}
+	or := chans.Or(ctx, dones...)
+	go func() {
+		<-or
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// Group - горутины конвейера с семантикой errgroup: первая ошибка отменяет контекст группы, по нему
+// останавливаются остальные стадии, а Wait дожидается всех горутин и возвращает эту первую ошибку.
+// Стадии, запущенные в группе, пишут в небуферизованные каналы, поэтому результат конвейера нужно
+// дочитать до конца (или отменить контекст) до вызова Wait
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// NewGroup создаёт группу, контекст которой производный от ctx
+func NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Context возвращает контекст группы: он отменяется при первой ошибке, отмене родителя или в конце Wait
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go запускает f в отдельной горутине с контекстом группы. Ошибка f отменяет контекст группы
+func (g *Group) Go(f func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(g.ctx); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait дожидается завершения всех горутин группы и возвращает первую ошибку
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
@@ -0,0 +1,226 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkLeaks проверяет в конце теста, что все горутины, запущенные во время теста, завершились
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("горутин %d, а до теста было %d:\n%s", runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+// collect читает канал до закрытия
+func collect[T any](in <-chan T) []T {
+	var res []T
+	for v := range in {
+		res = append(res, v)
+	}
+	return res
+}
+
+// naturals - бесконечный генератор 1, 2, 3, ...
+func naturals(ctx context.Context) <-chan int {
+	n := 0
+	return GenerateFunc(ctx, func() (int, bool) {
+		n++
+		return n, true
+	})
+}
+
+func TestMapFilter(t *testing.T) {
+	checkLeaks(t)
+	ctx := context.Background()
+	odd := Filter(ctx, Generate(ctx, 1, 2, 3, 4, 5), func(v int) bool { return v%2 == 1 })
+	squares := Map(ctx, odd, func(v int) int { return v * v })
+	if got, expected := collect(squares), []int{1, 9, 25}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("actual %v, expected %v", got, expected)
+	}
+}
+
+func TestWithDone(t *testing.T) {
+	checkLeaks(t)
+	stop := make(chan struct{})
+	never := make(chan struct{})
+	ctx, cancel := WithDone(context.Background(), never, stop)
+	defer cancel()
+
+	n := 0
+	for v := range naturals(ctx) { // Бесконечный генератор останавливается закрытием одного из done-каналов
+		if n = v; n == 100 {
+			close(stop)
+		}
+	}
+	if n < 100 {
+		t.Errorf("прочитано %d значений, ожидалось не меньше 100", n)
+	}
+	if ctx.Err() == nil {
+		t.Error("контекст не отменён после закрытия done-канала")
+	}
+}
+
+func TestPool(t *testing.T) {
+	checkLeaks(t)
+	g := NewGroup(context.Background())
+	var running, maxRunning int32
+	square := func(ctx context.Context, v int) (int, error) {
+		n := atomic.AddInt32(&running, 1)
+		for { // Запоминаем, сколько воркеров работало одновременно
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return v * v, nil
+	}
+	got := collect(Pool(g, Generate(g.Context(), 1, 2, 3, 4, 5, 6, 7, 8), 3, square))
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(got)
+	if expected := []int{1, 4, 9, 16, 25, 36, 49, 64}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("actual %v, expected %v", got, expected)
+	}
+	if maxRunning > 3 {
+		t.Errorf("одновременно работало %d воркеров, а их всего 3", maxRunning)
+	}
+}
+
+func TestOrderedPool(t *testing.T) {
+	checkLeaks(t)
+	g := NewGroup(context.Background())
+	values := make([]int, 50)
+	for i := range values {
+		values[i] = i
+	}
+	slow := func(ctx context.Context, v int) (int, error) {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond) // Результаты готовы не по порядку
+		return v * 10, nil
+	}
+	got := collect(OrderedPool(g, Generate(g.Context(), values...), 8, slow))
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if v != i*10 {
+			t.Fatalf("результат %d: actual %d, expected %d (%v)", i, v, i*10, got)
+		}
+	}
+	if len(got) != len(values) {
+		t.Errorf("получено %d результатов, ожидалось %d", len(got), len(values))
+	}
+}
+
+func TestPoolError(t *testing.T) {
+	errBad := errors.New("плохое значение")
+	pools := map[string]func(*Group, <-chan int, int, func(context.Context, int) (int, error)) <-chan int{
+		"Pool":        Pool[int, int],
+		"OrderedPool": OrderedPool[int, int],
+	}
+	for name, pool := range pools {
+		t.Run(name, func(t *testing.T) {
+			checkLeaks(t)
+			g := NewGroup(context.Background())
+			check := func(ctx context.Context, v int) (int, error) {
+				if v == 7 {
+					return 0, errBad
+				}
+				return v, nil
+			}
+			got := collect(pool(g, naturals(g.Context()), 4, check)) // Генератор бесконечный: остановит его только ошибка
+			if err := g.Wait(); err != errBad {
+				t.Errorf("actual %v, expected %v", err, errBad)
+			}
+			for _, v := range got {
+				if v == 7 {
+					t.Error("значение с ошибкой попало в результат")
+				}
+			}
+		})
+	}
+}
+
+func TestPoolWaitCoversAllGoroutines(t *testing.T) {
+	errBad := errors.New("плохое значение")
+	pools := map[string]func(*Group, <-chan int, int, func(context.Context, int) (int, error)) <-chan int{
+		"Pool":        Pool[int, int],
+		"OrderedPool": OrderedPool[int, int],
+	}
+	for name, pool := range pools {
+		t.Run(name, func(t *testing.T) {
+			checkLeaks(t)
+			g := NewGroup(context.Background())
+			fail := func(context.Context, int) (int, error) { return 0, errBad }
+			out := pool(g, naturals(g.Context()), 4, fail) // Результат не читаем: конвейер останавливает ошибка
+			if err := g.Wait(); err != errBad {
+				t.Errorf("actual %v, expected %v", err, errBad)
+			}
+			select { // Все горутины стадии завершились вместе с Wait, значит, результат уже закрыт
+			case _, ok := <-out:
+				if ok {
+					t.Error("после Wait в результате осталось значение")
+				}
+			default:
+				t.Error("результат не закрыт к возврату Wait")
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	checkLeaks(t)
+	const every = 20 * time.Millisecond
+	ctx := context.Background()
+	start := time.Now()
+	var times []time.Duration
+	for range RateLimit(ctx, Generate(ctx, 1, 2, 3, 4, 5), every, 2) {
+		times = append(times, time.Since(start))
+	}
+	if len(times) != 5 {
+		t.Fatalf("получено %d значений, ожидалось 5", len(times))
+	}
+	if times[1] >= every { // Первые два значения - одной пачкой
+		t.Errorf("второе значение через %v, ожидалось сразу", times[1])
+	}
+	if times[4] < 3*every-every/4 { // Остальные три - по одному за every
+		t.Errorf("пятое значение через %v, ожидалось не раньше %v", times[4], 3*every)
+	}
+
+	for _, every := range []time.Duration{0, -time.Second} { // Без ограничения: раньше здесь было деление на ноль
+		n := 0
+		for range RateLimit(ctx, Generate(ctx, 1, 2, 3), every, 1) {
+			n++
+		}
+		if n != 3 {
+			t.Errorf("every %v: получено %d значений, ожидалось 3", every, n)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	out := RateLimit(cancelled, naturals(cancelled), time.Hour, 1)
+	<-out
+	cancel() // Второе значение ждёт жетона час, но отмена контекста прерывает ожидание
+	for range out {
+	}
+}
@@ -0,0 +1,215 @@
+package pipeline
+
+import (
+	"context"
+	"dev07/chans"
+	"sync"
+	"time"
+)
+
+// Generate отправляет values по очереди и закрывает результат, когда они закончатся или будет отменён ctx
+func Generate[T any](ctx context.Context, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			if !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// GenerateFunc отправляет значения, которые возвращает next, пока она возвращает true и не отменён ctx.
+// Бесконечный генератор останавливается только отменой ctx
+func GenerateFunc[T any](ctx context.Context, next func() (T, bool)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := next()
+			if !ok || !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Map применяет f к каждому значению из in. Порядок значений сохраняется
+func Map[T, R any](ctx context.Context, in <-chan T, f func(T) R) <-chan R {
+	out := make(chan R)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := chans.Receive(ctx, in)
+			if !ok || !chans.Send(ctx, out, f(v)) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Filter пропускает значения из in, для которых keep возвращает true
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := chans.Receive(ctx, in)
+			if !ok {
+				return
+			}
+			if keep(v) && !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Pool обрабатывает значения из in функцией f в workers горутинах группы g. Результаты выходят в порядке
+// готовности. Ошибка f останавливает весь конвейер группы: её вернёт g.Wait, а результат закроется
+func Pool[T, R any](g *Group, in <-chan T, workers int, f func(context.Context, T) (R, error)) <-chan R {
+	if workers < 1 {
+		workers = 1
+	}
+	out := make(chan R)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		g.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for {
+				v, ok := chans.Receive(ctx, in)
+				if !ok {
+					return nil
+				}
+				r, err := f(ctx, v)
+				if err != nil {
+					return err
+				}
+				if !chans.Send(ctx, out, r) {
+					return nil
+				}
+			}
+		})
+	}
+	g.Go(func(context.Context) error { // Закрывает результат тоже горутина группы: к возврату g.Wait он уже закрыт
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// OrderedPool - то же, что Pool, но результаты выходят в порядке значений in. Вперёд самого старого
+// необработанного значения уходит не больше workers значений, так что медленное значение
+// не заставляет копить в памяти неограниченную очередь готовых результатов
+func OrderedPool[T, R any](g *Group, in <-chan T, workers int, f func(context.Context, T) (R, error)) <-chan R {
+	if workers < 1 {
+		workers = 1
+	}
+	type job struct {
+		v   T
+		res chan R // Буфер на одно значение: воркер не ждёт, пока результат заберут
+	}
+	jobs := make(chan job)
+	queue := make(chan chan R, workers) // Результаты в порядке поступления значений
+	out := make(chan R)
+
+	// Раздатчик и сборщик - тоже горутины группы, как и воркеры: g.Wait дожидается их всех
+	g.Go(func(ctx context.Context) error { // Раздаём значения воркерам, запоминая порядок
+		defer close(jobs)
+		defer close(queue)
+		for {
+			v, ok := chans.Receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			res := make(chan R, 1)
+			if !chans.Send(ctx, queue, res) || !chans.Send(ctx, jobs, job{v: v, res: res}) {
+				return nil
+			}
+		}
+	})
+	for i := 0; i < workers; i++ {
+		g.Go(func(ctx context.Context) error {
+			for {
+				j, ok := chans.Receive(ctx, jobs)
+				if !ok {
+					return nil
+				}
+				r, err := f(ctx, j.v)
+				if err != nil {
+					return err
+				}
+				j.res <- r
+			}
+		})
+	}
+	g.Go(func(ctx context.Context) error { // Выдаём результаты по порядку: ждём каждый, даже если следующие уже готовы
+		defer close(out)
+		for {
+			res, ok := chans.Receive(ctx, queue)
+			if !ok {
+				return nil
+			}
+			r, ok := chans.Receive(ctx, res)
+			if !ok || !chans.Send(ctx, out, r) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// RateLimit пропускает значения из in не чаще одного за every, но после простоя - пачкой до burst значений
+// подряд (token bucket: жетон копится раз в every, всего не больше burst). every <= 0 - без ограничения
+func RateLimit[T any](ctx context.Context, in <-chan T, every time.Duration, burst int) <-chan T {
+	if every <= 0 {
+		return chans.OrDone(ctx, in)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		tokens := burst
+		last := time.Now() // Когда последний раз пересчитывали жетоны
+		for {
+			v, ok := chans.Receive(ctx, in)
+			if !ok {
+				return
+			}
+			now := time.Now()
+			if gained := int(now.Sub(last) / every); gained > 0 {
+				tokens += gained
+				last = last.Add(time.Duration(gained) * every)
+			}
+			if tokens >= burst { // Полная пачка: время простоя сверх неё не копится
+				tokens = burst
+				last = now
+			}
+			if tokens == 0 {
+				timer := time.NewTimer(last.Add(every).Sub(now))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				tokens++
+				last = last.Add(every)
+			}
+			tokens--
+			if !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -38,49 +38,17 @@ package main
 //  "упразднить" дочернюю горутину.
 //  В данном случае мы пытаемся объединить один или несколько done-каналов в один done-канал, который закрывается, если закрывается какой-либо
 //  из составляющих его каналов.
+//  Сама реализация - в пакете chans: там же and-канал, первое значение, слияние, тройник и мост. В отличие от рекурсивной
+//  реализации, горутины chans.Or завершаются, как только закроется результат, а ждать можно и отмены контекста.
 import (
+"context"
+"dev07/chans"
 "fmt"
 "time"
 )
 // Функция, объединяющая один или несколько done-каналов в один done-канал
-func or(channs ...<-chan interface{}) <-chan interface{} { // вход: слайс (переменной длины) каналов любого типа, выход: один канал
-	// Поскольку это рекурсивная функция, мы должны установить критерии завершения (определить крайний случай рекурсии).
-	switch len(channs) {
-	case 0: // Если в функцию не было передано ни одного канала,
-		return nil // вернём nil channel
-	case 1: // второй критерий завершения гласит, что если наш слайс содержит только один элемент, мы просто возвращаем этот элемент
-		return channs[0]
-	}
-	// Дальше рассматриваем ситуации, когда каналов в функцию передано больше одного
-	orDone := make(chan interface{})
-	go func() { // создаем горутину, чтобы мы могли ждать сообщений на наших каналах без блокировки
-		defer close(orDone)
-
-		switch len(channs) {
-		// Если в ф-ю передано 2 канала, используем select case, чтобы читать из того канала, который готов передать значение
-		// В качестве оптимизации, чтобы ограничить количество запускаемых горутин, мы помещаем здесь особый случай для
-		// вызовов or только с двумя каналами
-		case 2:
-			select {
-			case <-channs[0]: // Читаем значение из канала
-			case <-channs[1]:
-			}
-		// В остальных случаях (каналов больше двух)
-		default:
-			select {
-			case <-channs[0]:
-			case <-channs[1]:
-			case <-channs[2]:
-			case <-or(append(channs[3:], orDone)...): // Рекурсивно создаем or-канал из всех каналов в срезе после третьего индекса
-													  // Каждый рекурсивный вызов обрабатывает максимум по 3 канала,
-													  // после чего происходит новый рекурсивный вызов. Крайним случаем будет
-													  // вызов or с кол-вом каналов, не превышающих 3
-			// Здесь мы рекурсивно создаем or-канал из всех каналов в нашем слайсе после третьего индекса
-			// Если закроется какой-то из каналов внутри функции, вызванной рекурсивно (на любом уровне рекурсии), закроется и этот канал
-			}
-		}
-	}()
-	return orDone
+var or = func(channs ...<-chan interface{}) <-chan interface{} { // вход: слайс (переменной длины) каналов любого типа, выход: один канал
+	return chans.Or(context.Background(), channs...) // Контекст не отменяется никогда: результат закроет только один из каналов
 }
 
 func main() {
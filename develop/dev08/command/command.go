@@ -2,13 +2,14 @@ package command
 
 import (
 	"bufio"
+	"dev08/parser"
 	"dev08/shell"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
-	"strings"
+	"strconv"
 )
 
 var (
@@ -19,6 +20,7 @@ var (
 	errConsoleInput    = errors.New("shell: чтение не удалось")
 	errPromptBuildFail = errors.New("shell: не удалось определить текущую директорию")
 	errBadUser         = errors.New("shell: не удалось получить информацию о текущем пользователе")
+	errNotFound        = errors.New("shell: команда не найдена")
 	successExitMessage = "shell: exit successful"
 )
 
@@ -27,6 +29,7 @@ type ShellTerminal struct {
 	shell  *shell.Shell
 	reader io.Reader
 	writer io.Writer
+	status int // Код завершения последней выполненной команды, $?
 }
 
 // New ...
@@ -48,39 +51,14 @@ func (s *ShellTerminal) Start() error {
 			return errPromptBuildFail
 		}
 		fmt.Fprint(s.writer, prompt)
-		scanner.Scan()
+		if !scanner.Scan() { // Конец ввода (Ctrl+D) завершает шелл так же, как \exit
+			break
+		}
 		text := scanner.Text()
 		if text == ShellExitCommand {
 			break
 		}
-		args := strings.Fields(text) // Разделяет строку на подстроки по следующим разделителям: '\t', '\n', '\v', '\f', '\r', ' ', U+0085 (NEL), U+00A0 (NBSP)
-		s.shell.SetArgs(args) // В зависимости от того, какую команду ввёл пользователь, выполняем соответствующий блок case
-		switch args[0] {
-		case "cd":
-			cdExecutor := &shell.CDExecutor{}
-			s.shell.SetExecutor(cdExecutor)
-		case "echo":
-			echoExecutor := &shell.EchoExecutor{}
-			s.shell.SetExecutor(echoExecutor)
-		case "ps":
-			psExecutor := &shell.PSExecutor{}
-			s.shell.SetExecutor(psExecutor)
-		case "pwd":
-			pwdExecutor := &shell.PWDExecutor{}
-			s.shell.SetExecutor(pwdExecutor)
-		case "kill":
-			killExecutor := &shell.KillProcessExecutor{}
-			s.shell.SetExecutor(killExecutor)
-
-		default:
-			fmt.Fprintln(s.writer, "shell: неизвестная команда")
-		}
-		res, err := s.shell.Start()
-		if err != nil {
-			fmt.Fprintln(s.writer, err.Error())
-			continue
-		}
-		fmt.Fprintln(s.writer, res)
+		s.run(text)
 	}
 	if scanner.Err() != nil {
 		return errConsoleInput
@@ -90,6 +68,35 @@ func (s *ShellTerminal) Start() error {
 	}
 	return nil
 }
+
+// run выполняет введённую строку: конвейеры списка по очереди, пропуская те, чьё условие && или || не выполнено
+func (s *ShellTerminal) run(line string) {
+	items, err := parser.Parse(line)
+	if err != nil {
+		fmt.Fprintln(s.writer, err.Error())
+		s.status = 2 // Как в bash: код завершения синтаксической ошибки
+		return
+	}
+	for i, item := range items {
+		if i > 0 && (item.Op == parser.OpAnd && s.status != 0 || item.Op == parser.OpOr && s.status == 0) {
+			continue // Код завершения остаётся от последней выполненной команды: "false || true && echo да" выведет "да"
+		}
+		s.status = s.runPipeline(item.Pipeline)
+	}
+}
+
+// lookup возвращает значение переменной для подстановки: $? - код завершения последней команды, $$ - PID шелла,
+// остальные берутся из окружения
+func (s *ShellTerminal) lookup(name string) string {
+	switch name {
+	case "?":
+		return strconv.Itoa(s.status)
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	}
+	return os.Getenv(name)
+}
+
 // buildPrompt конструирует приглашение командной строки, поскольку в зависимости от текущих рабочей директории или пользователя оно будет отличаться
 func (s *ShellTerminal) buildPrompt() (string, error) {
 	path, err := os.Getwd() // Getwd возвращает абсолютный путь до текущего (рабочего) каталога
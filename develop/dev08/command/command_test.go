@@ -0,0 +1,103 @@
+package command
+
+import (
+	"bytes"
+	"dev08/shell"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunLists(t *testing.T) {
+	t.Setenv("GREETING", "привет")
+	testTable := []struct {
+		line     string
+		expected string
+	}{
+		{`echo "hello   world"`, "hello   world\n"},
+		{`echo $GREETING, 'мир'; echo $?`, "привет, мир\n0\n"},
+		{`echo a && echo b || echo c`, "a\nb\n"},
+		{`unknown && echo a || echo b`, "shell: команда не найдена: unknown\nb\n"},
+		{`unknown; echo $?`, "shell: команда не найдена: unknown\n127\n"},
+		{`echo "a`, "shell: не закрыта кавычка\n"},
+	}
+	for _, tc := range testTable {
+		var out bytes.Buffer
+		terminal := New(shell.New(), strings.NewReader(""), &out)
+		terminal.run(tc.line)
+		if actual := out.String(); actual != tc.expected {
+			t.Errorf("%s: actual %q, expected %q", tc.line, actual, tc.expected)
+		}
+	}
+}
+
+func TestRunPipelines(t *testing.T) {
+	for _, name := range []string{"sh", "tr", "sort", "head", "cat", "true", "false"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("нет внешней команды %s: %v", name, err)
+		}
+	}
+	testTable := []struct {
+		line     string
+		expected string
+	}{
+		{`sh -c 'echo "$0 $1"' "hello world" x`, "hello world x\n"},
+		{`echo hello world | tr a-z A-Z`, "HELLO WORLD\n"},
+		{`sh -c 'printf "в\nа\nб\n"' | sort | head -n 2`, "а\nб\n"},
+		{`echo first | cat | cat | tr a-z A-Z`, "FIRST\n"},
+		{`sh -c 'exit 3'; echo $?`, "3\n"},
+		{`sh -c 'kill -TERM $$'; echo $?`, "143\n"}, // 128 + SIGTERM
+		{`false | true; echo $?`, "0\n"},            // Код завершения - последней команды конвейера
+		{`true | false || echo ошибка`, "ошибка\n"},
+		{`sh -c 'echo в stderr >&2' | cat`, "в stderr\n"}, // Ошибки не идут по конвейеру
+		{`echo a | unknown; echo $?`, "shell: команда не найдена: unknown\n127\n"},
+		{`./нет/такой/программы; echo $?`, "shell: команда не найдена: ./нет/такой/программы\n127\n"},
+		{`echo "a | b" | sh -c 'read line; echo "[$line]"'`, "[a | b]\n"},
+		{`sh -c 'echo 1' | echo два | cat`, "два\n"}, // Встроенная команда в середине вход не читает
+	}
+	for _, tc := range testTable {
+		var out bytes.Buffer
+		New(shell.New(), strings.NewReader(""), &out).run(tc.line)
+		if actual := out.String(); actual != tc.expected {
+			t.Errorf("%s: actual %q, expected %q", tc.line, actual, tc.expected)
+		}
+	}
+}
+
+func TestPipelineBuiltinsKeepWorkDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	testTable := []struct {
+		line     string
+		expected string
+	}{
+		{`cd ` + dir + ` | echo x`, "x\n"},
+		{`cd ` + dir + ` | echo x; echo $?`, "x\n0\n"},
+		{`cd /нет/такой/директории | cat; echo $?`, "chdir /нет/такой/директории: no such file or directory\n0\n"},
+		{`echo x | cd /нет/такой/директории; echo $?`, "chdir /нет/такой/директории: no such file or directory\n1\n"},
+	}
+	for _, tc := range testTable {
+		var out bytes.Buffer
+		New(shell.New(), strings.NewReader(""), &out).run(tc.line)
+		if actual := out.String(); actual != tc.expected {
+			t.Errorf("%s: actual %q, expected %q", tc.line, actual, tc.expected)
+		}
+		if actual, _ := os.Getwd(); actual != wd {
+			t.Fatalf("%s: рабочая директория сменилась на %s", tc.line, actual)
+		}
+	}
+}
+
+func TestStartStopsAtEOF(t *testing.T) {
+	var out bytes.Buffer
+	if err := New(shell.New(), strings.NewReader("echo a b\n"), &out).Start(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "a b\n") || !strings.HasSuffix(out.String(), successExitMessage+"\n") {
+		t.Errorf("неожиданный вывод %q", out.String())
+	}
+}
@@ -0,0 +1,157 @@
+package command
+
+import (
+	"dev08/parser"
+	"dev08/shell"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// runPipeline запускает команды конвейера одновременно, соединяя вывод каждой со входом следующей,
+// и возвращает код завершения последней команды, как bash без pipefail
+func (s *ShellTerminal) runPipeline(pipeline parser.Pipeline) int {
+	// На вход первой команде отдаём терминал, только если это файл (os.Stdin): иначе внешняя команда,
+	// которой нечего читать, ждала бы конца ввода шелла. Без файла внешние команды читают /dev/null
+	stdin, _ := s.reader.(*os.File)
+	if len(pipeline) == 1 { // Одиночная команда выполняется сразу, встроенные - в контексте s.shell
+		return s.runCommand(s.shell, pipeline[0], stdin, s.writer, s.writer)
+	}
+	out := s.writer
+	if _, ok := out.(*os.File); !ok { // В файл процессы пишут сами, а в остальное - через горутины exec, их записи нужно упорядочить
+		out = &lockedWriter{writer: out}
+	}
+	statuses := make([]int, len(pipeline))
+	var wg sync.WaitGroup
+	for i, cmd := range pipeline {
+		var stdout io.Writer = out
+		var r, w *os.File
+		if i < len(pipeline)-1 {
+			var err error
+			if r, w, err = os.Pipe(); err != nil {
+				fmt.Fprintln(out, err.Error())
+				statuses[len(statuses)-1] = 1
+				if i > 0 {
+					stdin.Close() // Иначе уже запущенная команда не дождётся конца ввода
+				}
+				break
+			}
+			stdout = w
+		}
+		wg.Add(1)
+		go func(i int, cmd parser.Command, stdin, w *os.File, stdout io.Writer) {
+			defer wg.Done()
+			// Встроенные команды конвейера работают одновременно, поэтому у каждой свой контекст. Как и в подоболочке bash,
+			// они не меняют состояние шелла: например, cd только проверяет директорию (см. shell.CDExecutor)
+			sh := shell.New()
+			sh.SetSubshell(true)
+			statuses[i] = s.runCommand(sh, cmd, stdin, stdout, out)
+			// Закрываем свои концы каналов: следующая команда получит конец ввода, а предыдущая,
+			// если ещё пишет, - ошибку записи (SIGPIPE), как и в других оболочках
+			if w != nil {
+				w.Close()
+			}
+			if i > 0 {
+				stdin.Close()
+			}
+		}(i, cmd, stdin, w, stdout)
+		stdin = r
+	}
+	wg.Wait()
+	return statuses[len(statuses)-1]
+}
+
+// runCommand раскрывает аргументы команды, выполняет её и возвращает код завершения.
+// Встроенные команды выполняются в контексте sh, остальные ищутся в PATH и запускаются отдельным процессом
+func (s *ShellTerminal) runCommand(sh *shell.Shell, cmd parser.Command, stdin *os.File, stdout, stderr io.Writer) int {
+	args := cmd.Expand(s.lookup)
+	if len(args) == 0 { // Команда состояла только из пустых переменных
+		return 0
+	}
+	sh.SetArgs(args) // В зависимости от того, какую команду ввёл пользователь, выполняем соответствующий блок case
+	switch args[0] {
+	case "cd":
+		cdExecutor := &shell.CDExecutor{}
+		sh.SetExecutor(cdExecutor)
+	case "echo":
+		echoExecutor := &shell.EchoExecutor{}
+		sh.SetExecutor(echoExecutor)
+	case "ps":
+		psExecutor := &shell.PSExecutor{}
+		sh.SetExecutor(psExecutor)
+	case "pwd":
+		pwdExecutor := &shell.PWDExecutor{}
+		sh.SetExecutor(pwdExecutor)
+	case "kill":
+		killExecutor := &shell.KillProcessExecutor{}
+		sh.SetExecutor(killExecutor)
+
+	default:
+		return runExternal(args, stdin, stdout, stderr)
+	}
+	res, err := sh.Start() // Встроенные команды вход не читают
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	fmt.Fprintln(stdout, res)
+	return 0
+}
+
+// runExternal запускает внешнюю команду, найденную в PATH (или по пути, если в имени есть '/'), и ждёт её завершения.
+// Коды завершения как в bash: 127 - команда не найдена, 126 - не удалось запустить, 128+N - процесс убит сигналом N
+func runExternal(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) { // Второе - для имени с путём: ./нет
+			fmt.Fprintf(stderr, "%v: %s\n", errNotFound, args[0])
+			return 127
+		}
+		fmt.Fprintf(stderr, "shell: %v\n", err)
+		return 126
+	}
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Args[0] = args[0] // Программа видит своё имя так, как его ввёл пользователь
+	if stdin != nil {     // Интерфейс с nil *os.File внутри - не nil, и exec пытался бы читать из него
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(stderr, "shell: %v\n", err)
+		return 126
+	}
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code
+		}
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return 1
+	default: // Ошибка копирования вывода, процесс при этом завершился
+		fmt.Fprintf(stderr, "shell: %v\n", err)
+		return 1
+	}
+}
+
+// lockedWriter пишет в writer из нескольких горутин по очереди
+type lockedWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writer.Write(p)
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Expand раскрывает аргументы команды (см. Word.Expand)
+func (c Command) Expand(lookup func(name string) string) []string {
+	var args []string
+	for _, w := range c.Words {
+		args = append(args, w.Expand(lookup)...)
+	}
+	return args
+}
+
+// Expand раскрывает слово: ~ и ~user в начале, переменные (их значения берутся из lookup) и шаблоны имён файлов
+// (*, ?, [...]) в тексте вне кавычек. Шаблон, которому не соответствует ни один файл, остаётся как есть, как в bash.
+// Значение переменной, как в zsh, не делится на слова и не считается шаблоном. Слово только из пустых переменных
+// вне кавычек пропадает совсем, а "" остаётся пустым аргументом
+func (w Word) Expand(lookup func(name string) string) []string {
+	parts := expandTilde(w, lookup)
+	var text, pattern strings.Builder // Результат без шаблонов и он же как шаблон для filepath.Glob
+	isPattern := false
+	onlyVars := true // Только переменные вне кавычек
+	for _, p := range parts {
+		value := p.Text
+		if p.Var {
+			value = lookup(p.Text)
+		}
+		if !p.Var || p.Quoted {
+			onlyVars = false
+		}
+		text.WriteString(value)
+		if p.Var || p.Quoted {
+			pattern.WriteString(escapePattern(value))
+			continue
+		}
+		pattern.WriteString(value)
+		if strings.ContainsAny(value, "*?[") {
+			isPattern = true
+		}
+	}
+	if onlyVars && text.Len() == 0 {
+		return nil
+	}
+	if isPattern {
+		if matches, err := filepath.Glob(pattern.String()); err == nil && len(matches) > 0 {
+			return matches
+		}
+	}
+	return []string{text.String()}
+}
+
+// expandTilde заменяет ~ или ~user в начале слова на домашний каталог. Приставка - до первой '/',
+// и все её символы должны быть вне кавычек: "~"/x и ~"user" не раскрываются
+func expandTilde(w Word, lookup func(name string) string) Word {
+	if len(w) == 0 || w[0].Var || w[0].Quoted || !strings.HasPrefix(w[0].Text, "~") {
+		return w
+	}
+	prefix, rest := w[0].Text, ""
+	if i := strings.IndexByte(prefix, '/'); i >= 0 {
+		prefix, rest = prefix[:i], prefix[i:]
+	} else if len(w) > 1 {
+		return w
+	}
+	home, ok := homeDir(prefix[1:], lookup)
+	if !ok {
+		return w
+	}
+	res := Word{{Text: home, Quoted: true}, {Text: rest}} // Каталог - как в кавычках: '*' в имени не шаблон
+	return append(res, w[1:]...)
+}
+
+// homeDir возвращает домашний каталог пользователя name, для пустого имени - текущего ($HOME)
+func homeDir(name string, lookup func(name string) string) (string, bool) {
+	if name == "" {
+		if home := lookup("HOME"); home != "" {
+			return home, true
+		}
+		home, err := os.UserHomeDir()
+		return home, err == nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", false
+	}
+	return u.HomeDir, true
+}
+
+// escapePattern экранирует в s символы шаблона, чтобы filepath.Glob искал их буквально
+func escapePattern(s string) string {
+	if !strings.ContainsAny(s, `*?[\`) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errUnclosedQuote     = errors.New("shell: не закрыта кавычка")
+	errTrailingBackslash = errors.New("shell: строка заканчивается обратной косой чертой")
+	errUnclosedBrace     = errors.New("shell: не закрыта фигурная скобка в ${...}")
+	errBadSubstitution   = errors.New("shell: неверная подстановка")
+	errBackground        = errors.New("shell: фоновый запуск (&) не поддерживается")
+)
+
+// tokenKind - вид лексемы: слово или оператор
+type tokenKind int
+
+const (
+	tokenWord      tokenKind = iota
+	tokenSemicolon           // ;
+	tokenAnd                 // &&
+	tokenOr                  // ||
+	tokenPipe                // |
+)
+
+// token - лексема командной строки
+type token struct {
+	kind tokenKind
+	word Word   // Для tokenWord
+	text string // Как оператор записан в строке: для сообщений об ошибках
+}
+
+// Part - кусок слова: текст или подстановка переменной
+type Part struct {
+	Text   string // Текст или имя переменной
+	Var    bool   // Подстановка значения переменной Text ($Text или ${Text})
+	Quoted bool   // Текст был в кавычках или экранирован: в нём не раскрываются ~ и шаблоны имён файлов
+}
+
+// Word - слово команды в том виде, как оно записано: до раскрытия переменных, ~ и шаблонов (см. Expand)
+type Word []Part
+
+// add дописывает к слову текст, объединяя его с предыдущим куском, если тот тоже текст с теми же кавычками
+func (w Word) add(text string, quoted bool) Word {
+	if n := len(w); n > 0 && !w[n-1].Var && w[n-1].Quoted == quoted {
+		w[n-1].Text += text
+		return w
+	}
+	return append(w, Part{Text: text, Quoted: quoted})
+}
+
+// lexer делит строку на слова и операторы
+type lexer struct {
+	input  string
+	pos    int
+	tokens []token
+}
+
+// lex разбивает строку на лексемы. Пробелы и табуляции разделяют слова, '#' в начале слова начинает комментарий до конца строки
+func lex(input string) ([]token, error) {
+	l := &lexer{input: input}
+	for {
+		for l.pos < len(l.input) && isBlank(l.input[l.pos]) {
+			l.pos++
+		}
+		if l.pos == len(l.input) || l.input[l.pos] == '#' {
+			return l.tokens, nil
+		}
+		switch rest := l.input[l.pos:]; {
+		case strings.HasPrefix(rest, "&&"):
+			l.operator(tokenAnd, "&&")
+		case strings.HasPrefix(rest, "||"):
+			l.operator(tokenOr, "||")
+		case rest[0] == '|':
+			l.operator(tokenPipe, "|")
+		case rest[0] == ';':
+			l.operator(tokenSemicolon, ";")
+		case rest[0] == '&':
+			return nil, errBackground
+		default:
+			word, err := l.word()
+			if err != nil {
+				return nil, err
+			}
+			l.tokens = append(l.tokens, token{kind: tokenWord, word: word})
+		}
+	}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isOperator сообщает, начинается ли с c оператор: он заканчивает слово так же, как пробел
+func isOperator(c byte) bool {
+	return c == ';' || c == '&' || c == '|'
+}
+
+func (l *lexer) operator(kind tokenKind, text string) {
+	l.tokens = append(l.tokens, token{kind: kind, text: text})
+	l.pos += len(text)
+}
+
+// word читает слово до пробела или оператора. Слово может состоять из нескольких частей: ab'c d'"$HOME"
+func (l *lexer) word() (Word, error) {
+	var w Word
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case isBlank(c) || isOperator(c):
+			return w, nil
+		case c == '\'': // В одинарных кавычках всё - текст, экранирования нет
+			end := strings.IndexByte(l.input[l.pos+1:], '\'')
+			if end < 0 {
+				return nil, errUnclosedQuote
+			}
+			w = w.add(l.input[l.pos+1:l.pos+1+end], true)
+			l.pos += end + 2
+		case c == '"':
+			l.pos++
+			var err error
+			if w, err = l.doubleQuoted(w); err != nil {
+				return nil, err
+			}
+		case c == '\\': // Вне кавычек обратная косая черта экранирует любой следующий символ
+			if l.pos+1 == len(l.input) {
+				return nil, errTrailingBackslash
+			}
+			_, size := utf8.DecodeRuneInString(l.input[l.pos+1:])
+			w = w.add(l.input[l.pos+1:l.pos+1+size], true)
+			l.pos += 1 + size
+		case c == '$':
+			var err error
+			if w, err = l.variable(w, false); err != nil {
+				return nil, err
+			}
+		default:
+			w = w.add(l.input[l.pos:l.pos+1], false)
+			l.pos++
+		}
+	}
+	return w, nil
+}
+
+// doubleQuoted читает текст в двойных кавычках (открывающая уже прочитана). Внутри раскрываются переменные,
+// а обратная косая черта экранирует только $, `, ", \ и перевод строки - перед остальными символами она остаётся как есть
+func (l *lexer) doubleQuoted(w Word) (Word, error) {
+	w = w.add("", true) // "" - тоже слово, хоть и пустое
+	for l.pos < len(l.input) {
+		switch c := l.input[l.pos]; {
+		case c == '"':
+			l.pos++
+			return w, nil
+		case c == '\\' && l.pos+1 < len(l.input) && strings.IndexByte("$`\"\\\n", l.input[l.pos+1]) >= 0:
+			w = w.add(l.input[l.pos+1:l.pos+2], true)
+			l.pos += 2
+		case c == '$':
+			var err error
+			if w, err = l.variable(w, true); err != nil {
+				return nil, err
+			}
+		default:
+			w = w.add(l.input[l.pos:l.pos+1], true)
+			l.pos++
+		}
+	}
+	return nil, errUnclosedQuote
+}
+
+// variable читает подстановку: $NAME, ${NAME}, $? (код завершения последней команды) или $$ (PID шелла).
+// '$', за которым нет имени, - это просто символ '$'
+func (l *lexer) variable(w Word, quoted bool) (Word, error) {
+	l.pos++ // '$'
+	rest := l.input[l.pos:]
+	var name string
+	switch {
+	case strings.HasPrefix(rest, "{"):
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return nil, errUnclosedBrace
+		}
+		name = rest[1:end]
+		if name != "?" && name != "$" && nameLength(name) != len(name) || name == "" {
+			return nil, fmt.Errorf("%w: %s", errBadSubstitution, rest[:end+1])
+		}
+		l.pos += end + 1
+	case strings.HasPrefix(rest, "?") || strings.HasPrefix(rest, "$"):
+		name = rest[:1]
+		l.pos++
+	default:
+		n := nameLength(rest)
+		if n == 0 {
+			return w.add("$", quoted), nil
+		}
+		name = rest[:n]
+		l.pos += n
+	}
+	return append(w, Part{Text: name, Var: true, Quoted: quoted}), nil
+}
+
+// nameLength возвращает длину имени переменной в начале s: латинские буквы, цифры и '_', не с цифры
+func nameLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9' {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
@@ -0,0 +1,71 @@
+// Package parser разбирает командную строку шелла: слова в одинарных и двойных кавычках, экранирование,
+// подстановку переменных ($VAR, ${VAR}), ~, шаблоны имён файлов, конвейеры через | и списки конвейеров через ;, && и ||
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errSyntax = errors.New("shell: синтаксическая ошибка рядом с")
+)
+
+// Operator - чем команда связана с предыдущей в списке
+type Operator int
+
+const (
+	OpSeq Operator = iota // ; - выполнить в любом случае
+	OpAnd                 // && - только если предыдущая завершилась успешно
+	OpOr                  // || - только если предыдущая завершилась с ошибкой
+)
+
+// Command - простая команда: имя и аргументы, ещё не раскрытые
+type Command struct {
+	Words []Word
+}
+
+// Pipeline - команды конвейера: вывод каждой идёт на вход следующей
+type Pipeline []Command
+
+// Item - конвейер списка и условие его запуска. У первого конвейера Op всегда OpSeq
+type Item struct {
+	Op       Operator
+	Pipeline Pipeline
+}
+
+// Parse разбирает строку в список конвейеров. Пустая строка (или только комментарий) - пустой список.
+// Как и в bash, условия && и || проверяются слева направо по коду завершения последней выполненной команды
+func Parse(line string) ([]Item, error) {
+	tokens, err := lex(line)
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	op := OpSeq
+	var pipeline Pipeline
+	var cmd Command
+	for _, t := range tokens {
+		if t.kind == tokenWord {
+			cmd.Words = append(cmd.Words, t.word)
+			continue
+		}
+		if len(cmd.Words) == 0 { // Оператор без команды перед ним: "; ls", "ls && && pwd", "| grep go"
+			return nil, fmt.Errorf("%w %q", errSyntax, t.text)
+		}
+		pipeline = append(pipeline, cmd)
+		cmd = Command{}
+		if t.kind == tokenPipe {
+			continue
+		}
+		items = append(items, Item{Op: op, Pipeline: pipeline})
+		pipeline = nil
+		op = map[tokenKind]Operator{tokenSemicolon: OpSeq, tokenAnd: OpAnd, tokenOr: OpOr}[t.kind]
+	}
+	if len(cmd.Words) > 0 {
+		items = append(items, Item{Op: op, Pipeline: append(pipeline, cmd)})
+	} else if op != OpSeq || len(pipeline) > 0 { // "ls &&", "ls |" - после &&, || и | нужна команда, а ";" в конце допустима
+		return nil, fmt.Errorf("%w концом строки", errSyntax)
+	}
+	return items, nil
+}
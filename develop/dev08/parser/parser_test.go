@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// env - переменные для подстановки в тестах
+var env = map[string]string{
+	"HOME":  "/home/user",
+	"NAME":  "мир",
+	"SPACE": "a  b",
+	"STAR":  "*",
+	"EMPTY": "",
+}
+
+func lookup(name string) string {
+	return env[name]
+}
+
+// expandLine разбирает строку из одной команды и раскрывает её аргументы
+func expandLine(t *testing.T, line string) []string {
+	t.Helper()
+	items, err := Parse(line)
+	if err != nil {
+		t.Fatalf("%q: %v", line, err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("%q: команд %d, ожидалась одна", line, len(items))
+	}
+	return items[0].Pipeline[0].Expand(lookup)
+}
+
+func TestWords(t *testing.T) {
+	testTable := []struct {
+		line     string
+		expected []string
+	}{
+		{`echo hello world`, []string{"echo", "hello", "world"}},
+		{"  echo\t hello  ", []string{"echo", "hello"}},
+		{`echo "hello world"`, []string{"echo", "hello world"}},
+		{`echo 'hello   world'`, []string{"echo", "hello   world"}},
+		{`echo ab"c d"'e f'g`, []string{"echo", "abc de fg"}},
+		{`echo "" ''`, []string{"echo", "", ""}},
+		{`echo hello\ world \"q\" \\`, []string{"echo", "hello world", `"q"`, `\`}},
+		{`echo "a\"b" "a\b" "\$NAME" "\\"`, []string{"echo", `a"b`, `a\b`, "$NAME", `\`}},
+		{`echo 'a\b' '$NAME' 'a"b'`, []string{"echo", `a\b`, "$NAME", `a"b`}},
+		{`echo привет\ мир`, []string{"echo", "привет мир"}},
+		{`echo # комментарий`, []string{"echo"}},
+		{`echo a#b`, []string{"echo", "a#b"}},
+	}
+	for _, tc := range testTable {
+		if actual := expandLine(t, tc.line); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: actual %q, expected %q", tc.line, actual, tc.expected)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	testTable := []struct {
+		line     string
+		expected []string
+	}{
+		{`echo $NAME ${NAME}`, []string{"echo", "мир", "мир"}},
+		{`echo привет,$NAME! "привет, $NAME" 'привет, $NAME'`, []string{"echo", "привет,мир!", "привет, мир", "привет, $NAME"}},
+		{`echo ${NAME}_1 $NAME_1 $NAMEы`, []string{"echo", "мир_1", "миры"}}, // Имя - только латиница, цифры и '_'
+		{`echo $SPACE`, []string{"echo", "a  b"}},                    // Значение не делится на слова
+		{`echo $UNSET x $EMPTY`, []string{"echo", "x"}},              // Пустые переменные вне кавычек пропадают
+		{`echo "$UNSET" x"$EMPTY"`, []string{"echo", "", "x"}},       // ...а в кавычках остаются пустым словом
+		{`echo $ a$ $1 "$"`, []string{"echo", "$", "a$", "$1", "$"}}, // Без имени '$' - просто символ
+		{`echo ~ ~/dir a~ "~" \~ ~"/x"`, []string{"echo", "/home/user", "/home/user/dir", "a~", "~", "~", "~/x"}},
+		{`echo ~/$NAME`, []string{"echo", "/home/user/мир"}},
+	}
+	for _, tc := range testTable {
+		if actual := expandLine(t, tc.line); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: actual %q, expected %q", tc.line, actual, tc.expected)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.go", "*.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env["DIR"] = dir
+	defer delete(env, "DIR")
+	in := func(names ...string) []string {
+		res := []string{"ls"}
+		for _, name := range names {
+			res = append(res, filepath.Join(dir, name))
+		}
+		return res
+	}
+	testTable := []struct {
+		line     string
+		expected []string
+	}{
+		{`ls $DIR/*.txt`, in("*.txt", "a.txt", "b.txt")},
+		{`ls $DIR/?.go $DIR/[ab].txt`, in("c.go", "a.txt", "b.txt")},
+		{`ls "$DIR"/'*'.txt $DIR/\*.txt`, in("*.txt", "*.txt")}, // Экранированная звёздочка ищется буквально
+		{`ls "$DIR/*.txt"`, []string{"ls", dir + "/*.txt"}},     // В кавычках шаблон не раскрывается
+		{`ls $DIR/$STAR`, []string{"ls", dir + "/*"}},           // ...и в значении переменной тоже
+		{`ls $DIR/*.md`, []string{"ls", dir + "/*.md"}},         // Нет совпадений - слово остаётся как есть
+	}
+	for _, tc := range testTable {
+		if actual := expandLine(t, tc.line); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: actual %q, expected %q", tc.line, actual, tc.expected)
+		}
+	}
+}
+
+func TestLists(t *testing.T) {
+	testTable := []struct {
+		line     string
+		expected []Operator
+	}{
+		{``, nil},
+		{`# только комментарий`, nil},
+		{`pwd`, []Operator{OpSeq}},
+		{`cd /tmp; pwd;`, []Operator{OpSeq, OpSeq}},
+		{`cd /tmp&&pwd||echo нет`, []Operator{OpSeq, OpAnd, OpOr}},
+		{`echo "a;b" 'a&&b' a\|\|b`, []Operator{OpSeq}},
+	}
+	for _, tc := range testTable {
+		items, err := Parse(tc.line)
+		if err != nil {
+			t.Errorf("%s: %v", tc.line, err)
+			continue
+		}
+		var actual []Operator
+		for _, item := range items {
+			actual = append(actual, item.Op)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: actual %v, expected %v", tc.line, actual, tc.expected)
+		}
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	items, err := Parse(`ps | grep "go run" | wc -l && echo '|'|cat; pwd`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual [][][]string
+	for _, item := range items {
+		var pipeline [][]string
+		for _, cmd := range item.Pipeline {
+			pipeline = append(pipeline, cmd.Expand(lookup))
+		}
+		actual = append(actual, pipeline)
+	}
+	expected := [][][]string{
+		{{"ps"}, {"grep", "go run"}, {"wc", "-l"}},
+		{{"echo", "|"}, {"cat"}},
+		{{"pwd"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %q, expected %q", actual, expected)
+	}
+	if items[1].Op != OpAnd || items[2].Op != OpSeq {
+		t.Errorf("actual операторы %v, %v, expected %v, %v", items[1].Op, items[2].Op, OpAnd, OpSeq)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testTable := []struct {
+		line     string
+		expected error
+	}{
+		{`echo "hello`, errUnclosedQuote},
+		{`echo 'hello`, errUnclosedQuote},
+		{`echo hello\`, errTrailingBackslash},
+		{`echo ${NAME`, errUnclosedBrace},
+		{`echo ${}`, errBadSubstitution},
+		{`echo ${A B}`, errBadSubstitution},
+		{`; pwd`, errSyntax},
+		{`pwd ;; pwd`, errSyntax},
+		{`pwd && || pwd`, errSyntax},
+		{`pwd &&`, errSyntax},
+		{`sleep 1 &`, errBackground},
+		{`| grep go`, errSyntax},
+		{`ps | | grep go`, errSyntax},
+		{`ps || | grep go`, errSyntax},
+		{`ps |`, errSyntax},
+	}
+	for _, tc := range testTable {
+		if _, err := Parse(tc.line); !errors.Is(err, tc.expected) {
+			t.Errorf("%s: actual %v, expected %v", tc.line, err, tc.expected)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/user"
 	"strings"
+	"syscall"
 )
 
 var (
@@ -26,25 +27,43 @@ type CDExecutor struct{}
 
 // Execute ...
 func (c *CDExecutor) Execute(s *Shell) (string, error) {
+	var dir string
 	// Если аргументом к команде "cd" не передан путь, по которому нужно перейти...
 	if len(s.Args) == 1 {
 		cUser, err := user.Current() //...то получаем текущего пользователя...
 		if err != nil {
 			return "", err
 		}
-		if err := os.Chdir(cUser.HomeDir); err != nil { // ...а затем меняем рабочую директорию на домашнюю для текущего пользователя.
-			return "", err
-		} // ...
+		dir = cUser.HomeDir // ...и переходим в его домашнюю директорию.
 	} else { // Если помимо команды "cd" пользователь указал путь до директории, которую нужно установить в качестве рабочей...
-		if err := os.Chdir(s.Args[1]); err != nil { // ...пытаемся сделать это и возвращаем ненулевое значение ошибки если попытка оказалась неудачной...
+		dir = s.Args[1]
+	}
+	if s.Subshell { // В конвейере только проверяем директорию: рабочая директория у процесса шелла одна на всех, а в bash cd в конвейере её не меняет
+		if err := checkDir(dir); err != nil {
 			return "", err
 		}
-
+	} else if err := os.Chdir(dir); err != nil { // ...пытаемся сделать это и возвращаем ненулевое значение ошибки если попытка оказалась неудачной...
+		return "", err
 	}
 
 	return "Directory changed successfully", nil // ... в притивном случае возвращаем сообщение об успехе
 }
 
+// checkDir возвращает ту же ошибку, что вернул бы os.Chdir(dir), но рабочую директорию не меняет
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if pathErr, ok := err.(*os.PathError); ok {
+			pathErr.Op = "chdir"
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "chdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
 // EchoExecutor ...
 type EchoExecutor struct{}
 
@@ -54,7 +73,7 @@ func (e *EchoExecutor) Execute(s *Shell) (string, error) {
 	if len(s.Args) == 1 {
 		return "", errEmptyEcho // ...возвращаем ошибку
 	}
-	return s.Args[1], nil
+	return strings.Join(s.Args[1:], " "), nil // Как и echo в других оболочках, выводим все аргументы через пробел
 }
 
 
@@ -6,6 +6,7 @@ package shell
 type Shell struct {
 	Args     []string
 	Executor Executor // Будем устанавливать Executor конкретного типа, в зависимости от введённой пользователем команды
+	Subshell bool     // Команда выполняется в конвейере: как и в подоболочке bash, она не меняет состояние самого шелла
 }
 
 // New ...
@@ -18,6 +19,11 @@ func (s *Shell) SetArgs(args []string) {
 	s.Args = args
 }
 
+// SetSubshell ...
+func (s *Shell) SetSubshell(subshell bool) {
+	s.Subshell = subshell
+}
+
 // SetExecutor ...
 func (s *Shell) SetExecutor(e Executor) {
 	s.Executor = e
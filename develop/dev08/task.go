@@ -48,7 +48,7 @@ func main() {
 	}
 
 	s := shell.New()
-	commander := command.New(s, os.Stdout, os.Stdin)
+	commander := command.New(s, os.Stdin, os.Stdout)
 	if err := commander.Start(); err != nil {
 		log.Fatal(err)
 	}